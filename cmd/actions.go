@@ -0,0 +1,64 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/command"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+// execPrefix marks gesture actions that run a command from [actions]
+const execPrefix = "exec:"
+
+// runCommandAction runs the named [actions] entry in the background,
+// logging its output and exit status and optionally flashing it on the OLED
+func (app *Application) runCommandAction(name string) {
+	action, exists := config.GlobalConfig.GetCommandAction(name)
+	if !exists {
+		log.Printf("Unknown command action: %s", name)
+		return
+	}
+
+	app.commandMutex.Lock()
+	if app.runningCommands[name] {
+		app.commandMutex.Unlock()
+		log.Printf("Command action %s is already running", name)
+		return
+	}
+	app.runningCommands[name] = true
+	app.commandMutex.Unlock()
+
+	if action.Show && app.hasOLED {
+		app.flashLines([]string{name, "Running..."}, 2*time.Second)
+	}
+
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			app.commandMutex.Lock()
+			delete(app.runningCommands, name)
+			app.commandMutex.Unlock()
+		}()
+
+		log.Printf("Running command action %s: %s", name, strings.Join(action.Args, " "))
+		result := command.Run(app.ctx, action)
+
+		for _, line := range strings.Split(strings.TrimSpace(result.Output), "\n") {
+			if line != "" {
+				log.Printf("[%s] %s", name, line)
+			}
+		}
+		if result.Err != nil {
+			log.Printf("Command action %s failed after %s: %v", name, result.Duration.Round(time.Millisecond), result.Err)
+		} else {
+			log.Printf("Command action %s finished in %s with exit status %d", name, result.Duration.Round(time.Millisecond), result.ExitCode)
+		}
+
+		if action.Show && app.hasOLED {
+			app.flashLines([]string{name + ": " + result.Summary(), result.LastLine()}, 5*time.Second)
+		}
+	}()
+}
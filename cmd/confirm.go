@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/power"
+)
+
+// pendingConfirm is a destructive action waiting for a second matching button event
+type pendingConfirm struct {
+	event    string
+	action   string
+	deadline time.Time
+	stopCh   chan struct{}
+}
+
+// actionLabels are the human readable names shown in confirm prompts
+var actionLabels = map[string]string{
+	"reboot":   "reboot",
+	"poweroff": "power off",
+}
+
+// requestConfirmedAction runs a destructive action, asking for confirmation
+// first when [key] confirm is enabled
+func (app *Application) requestConfirmedAction(event, action string) {
+	cfg := config.GlobalConfig
+	if !cfg.Key.Confirm || !app.hasOLED {
+		app.runPowerAction(action)
+		return
+	}
+
+	window := time.Duration(cfg.Time.Confirm * float64(time.Second))
+	if window <= 0 {
+		window = 5 * time.Second
+	}
+
+	pending := &pendingConfirm{
+		event:    event,
+		action:   action,
+		deadline: time.Now().Add(window),
+		stopCh:   make(chan struct{}),
+	}
+
+	app.confirmMutex.Lock()
+	if app.confirm != nil {
+		close(app.confirm.stopCh)
+	}
+	app.confirm = pending
+	app.confirmMutex.Unlock()
+
+	log.Printf("Waiting %.0fs for confirmation of %s (%s again)", window.Seconds(), action, event)
+	go app.confirmCountdown(pending)
+}
+
+// confirmCountdown shows the remaining confirmation time and cancels the action when it runs out
+func (app *Application) confirmCountdown(pending *pendingConfirm) {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		remaining := time.Until(pending.deadline)
+		if remaining <= 0 {
+			break
+		}
+
+		select {
+		case <-pending.stopCh:
+			return
+		default:
+		}
+
+		app.oledController.ShowLines([]string{
+			"Press again to",
+			fmt.Sprintf("%s (%.0fs)", actionLabels[pending.action], remaining.Seconds()),
+			"Other: cancel",
+		})
+
+		select {
+		case <-pending.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+
+	app.confirmMutex.Lock()
+	expired := app.confirm == pending
+	if expired {
+		app.confirm = nil
+	}
+	app.confirmMutex.Unlock()
+
+	if expired {
+		log.Printf("Confirmation of %s timed out", pending.action)
+		app.flashLines([]string{"Cancelled"}, 2*time.Second)
+	}
+}
+
+// handleConfirmEvent consumes a button event while a confirmation is pending.
+// It returns false when nothing is pending so the event is handled normally.
+func (app *Application) handleConfirmEvent(event string) bool {
+	app.confirmMutex.Lock()
+	pending := app.confirm
+	app.confirm = nil
+	app.confirmMutex.Unlock()
+
+	if pending == nil {
+		return false
+	}
+	close(pending.stopCh)
+
+	if event != pending.event || time.Now().After(pending.deadline) {
+		log.Printf("Confirmation of %s cancelled by %s", pending.action, event)
+		app.flashLines([]string{"Cancelled"}, 2*time.Second)
+		return true
+	}
+
+	log.Printf("Confirmed %s", pending.action)
+	app.runPowerAction(pending.action)
+	return true
+}
+
+// runPowerAction performs a reboot or poweroff requested from a button mapping
+func (app *Application) runPowerAction(action string) {
+	log.Printf("%s requested via button", action)
+	if err := app.powerAction(action); err != nil {
+		log.Printf("Refusing %s: %v", action, err)
+		if app.hasOLED {
+			app.flashLines([]string{"Refused " + action, err.Error()}, 3*time.Second)
+		}
+		return
+	}
+}
+
+// powerAction reboots or powers off the system unless a guard refuses it.
+// Failures of the request itself are reported on the OLED once known.
+func (app *Application) powerAction(action string) error {
+	// Interrupting a resync leaves the array degraded until it restarts from scratch
+	if status, syncing := app.sysInfo.GetRAIDSyncStatus(); syncing {
+		return fmt.Errorf("%s", status)
+	}
+
+	result := app.executeSystemCommand(power.Action(action))
+	go func() {
+		if err := <-result; err != nil {
+			log.Printf("Failed to execute %s: %v", action, err)
+			if app.hasOLED {
+				app.closeMenu()
+				app.flashLines([]string{actionLabels[action] + " failed", err.Error()}, 5*time.Second)
+			}
+		}
+	}()
+	return nil
+}
+
+// flashLines shows a short message on the OLED and returns to the pages afterwards
+func (app *Application) flashLines(lines []string, duration time.Duration) {
+	app.oledController.ShowLines(lines)
+	time.AfterFunc(duration, func() {
+		app.confirmMutex.Lock()
+		pending := app.confirm != nil
+		app.confirmMutex.Unlock()
+
+		if !pending && !app.isMenuOpen() {
+			app.oledController.ClearOverlay()
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/command"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/power"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/sysinfo"
+)
+
+// criticalCheckInterval is how often temperatures are compared to [critical]
+const criticalCheckInterval = 5 * time.Second
+
+// maxPowerOffBackoff caps the delay between failed overheat poweroff requests
+const maxPowerOffBackoff = 2 * time.Minute
+
+// criticalTempMonitor powers the system off when the CPU or a disk stays
+// above its critical temperature for longer than the grace period. It runs
+// independently of the fan controller, so it also protects a fan that was
+// switched off with the "switch" action.
+func (app *Application) criticalTempMonitor() {
+	defer app.wg.Done()
+	defer app.recoverPanic()
+
+	ticker := time.NewTicker(criticalCheckInterval)
+	defer ticker.Stop()
+
+	var since time.Time
+
+	// After the grace period the shutdown sequence runs once, then only the
+	// poweroff request is retried with a growing delay
+	var prepared bool
+	var retryAt time.Time
+	backoff := criticalCheckInterval
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case now := <-ticker.C:
+			cfg := config.GlobalConfig.Critical
+			source, temp, critical := app.checkCriticalTemp()
+
+			if !critical {
+				if !since.IsZero() {
+					log.Printf("Temperature recovered (%s %.1f°C), shutdown cancelled", source, temp)
+					app.fanController.SetForcedFull(false)
+					if app.hasOLED {
+						app.oledController.ClearAlert()
+					}
+					if prepared {
+						app.thawState()
+						prepared = false
+						retryAt = time.Time{}
+						backoff = criticalCheckInterval
+					}
+					since = time.Time{}
+				}
+				continue
+			}
+
+			if since.IsZero() {
+				since = now
+				log.Printf("CRITICAL: %s temperature %.1f°C, powering off in %.0fs unless it recovers", source, temp, cfg.Grace)
+
+				// Whatever the fan was doing, it has to run at full speed now.
+				// Forcing leaves the mode and override for after recovery.
+				app.fanController.SetForcedFull(true)
+
+				if cfg.Hook != "" {
+					app.runCriticalHook(cfg.Hook, source, temp)
+				}
+			}
+
+			remaining := time.Duration(cfg.Grace*float64(time.Second)) - now.Sub(since)
+			if app.hasOLED {
+				app.closeMenu()
+				app.oledController.ShowAlert([]string{
+					"OVERHEAT!",
+					fmt.Sprintf("%s %.1fC", source, temp),
+					fmt.Sprintf("Power off in %.0fs", max(remaining.Seconds(), 0)),
+				})
+			}
+
+			if remaining > 0 || now.Before(retryAt) {
+				continue
+			}
+
+			if !prepared {
+				log.Printf("CRITICAL: %s temperature %.1f°C did not recover, powering off", source, temp)
+				app.freezeState()
+				app.prepareShutdown(power.PowerOff)
+				prepared = true
+			}
+
+			if err := power.Execute(power.PowerOff); err != nil {
+				// The fan stays forced to full speed and the disks parked
+				log.Printf("Overheat poweroff failed, retrying in %s: %v", backoff, err)
+				retryAt = now.Add(backoff)
+				backoff = min(backoff*2, maxPowerOffBackoff)
+				continue
+			}
+			return
+		}
+	}
+}
+
+// checkCriticalTemp returns the hottest source above its critical threshold.
+// When nothing is critical it returns the CPU temperature.
+func (app *Application) checkCriticalTemp() (string, float64, bool) {
+	cfg := config.GlobalConfig.Critical
+
+	cpuTemp, err := sysinfo.ReadCPUTemperature()
+	if err == nil && cfg.Temp > 0 && cpuTemp >= cfg.Temp {
+		return "CPU", cpuTemp, true
+	}
+
+	if diskTemp, ok := app.diskController.GetMaxTemperature(); ok && cfg.DiskTemp > 0 && diskTemp >= cfg.DiskTemp {
+		return "Disk", diskTemp, true
+	}
+
+	return "CPU", cpuTemp, false
+}
+
+// runCriticalHook runs the [critical] hook in the background with the
+// temperature in its environment
+func (app *Application) runCriticalHook(hook, source string, temp float64) {
+	args, err := config.SplitCommandLine(hook)
+	if err != nil || len(args) == 0 {
+		log.Printf("Invalid critical hook %q: %v", hook, err)
+		return
+	}
+
+	action := &config.CommandAction{
+		Name:    "critical-hook",
+		Args:    args,
+		Timeout: 30 * time.Second,
+		Env: []string{
+			"ROCKPI_CRITICAL_SOURCE=" + source,
+			fmt.Sprintf("ROCKPI_CRITICAL_TEMP=%.1f", temp),
+		},
+	}
+
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+
+		result := command.Run(app.ctx, action)
+		if output := strings.TrimSpace(result.Output); output != "" {
+			log.Printf("[critical-hook] %s", output)
+		}
+		log.Printf("Critical hook finished: %s", result.Summary())
+	}()
+}
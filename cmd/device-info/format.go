@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+// Keys compared between the current configuration and the detected one
+var comparedKeys = []string{"BUTTON_CHIP", "BUTTON_LINE", "FAN_CHIP", "FAN_LINE", "FAN_POLARITY", "HARDWARE_PWM"}
+
+func validFormat(format string) bool {
+	switch format {
+	case "text", "json", "yaml", "env":
+		return true
+	}
+	return false
+}
+
+// writeReport prints the report in a machine-readable format
+func writeReport(w io.Writer, report config.DetectionReport, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case "yaml":
+		_, err := io.WriteString(w, formatYAML(report))
+		return err
+	case "env":
+		_, err := io.WriteString(w, formatEnv(report))
+		return err
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// formatYAML renders the report as YAML with the same keys and order as the
+// JSON output. Strings are always double quoted.
+func formatYAML(r config.DetectionReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "schema_version: %d\n", r.SchemaVersion)
+	fmt.Fprintf(&b, "board_type: %s\n", strconv.Quote(r.BoardType))
+	fmt.Fprintf(&b, "model: %s\n", strconv.Quote(r.Model))
+	fmt.Fprintf(&b, "confidence: %d\n", r.Confidence)
+	writeYAMLStrings(&b, "", "env", r.Env, config.EnvVarKeys(r.Env))
+
+	if len(r.Access) == 0 {
+		b.WriteString("access: {}\n")
+	} else {
+		b.WriteString("access:\n")
+		for _, key := range config.AccessKeys(r.Access) {
+			fmt.Fprintf(&b, "  %s: %t\n", key, r.Access[key])
+		}
+	}
+	fmt.Fprintf(&b, "verified: %t\n", r.Verified)
+	writeYAMLList(&b, "", "notes", r.Notes)
+
+	if c := r.Comparison; c != nil {
+		b.WriteString("comparison:\n")
+		writeYAMLStrings(&b, "  ", "current", c.Current, config.EnvVarKeys(c.Current))
+		writeYAMLList(&b, "  ", "differences", c.Differences)
+	}
+	return b.String()
+}
+
+// writeYAMLStrings writes a mapping of quoted strings at the indent
+func writeYAMLStrings(b *strings.Builder, indent, name string, values map[string]string, keys []string) {
+	if len(keys) == 0 {
+		fmt.Fprintf(b, "%s%s: {}\n", indent, name)
+		return
+	}
+	fmt.Fprintf(b, "%s%s:\n", indent, name)
+	for _, key := range keys {
+		fmt.Fprintf(b, "%s  %s: %s\n", indent, key, strconv.Quote(values[key]))
+	}
+}
+
+// writeYAMLList writes a sequence of quoted strings at the indent
+func writeYAMLList(b *strings.Builder, indent, name string, values []string) {
+	if len(values) == 0 {
+		fmt.Fprintf(b, "%s%s: []\n", indent, name)
+		return
+	}
+	fmt.Fprintf(b, "%s%s:\n", indent, name)
+	for _, value := range values {
+		fmt.Fprintf(b, "%s  - %s\n", indent, strconv.Quote(value))
+	}
+}
+
+// formatEnv renders the recommended variables as an env file, with the rest
+// of the report as comments
+func formatEnv(r config.DetectionReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# board_type=%s confidence=%d verified=%t\n", r.BoardType, r.Confidence, r.Verified)
+	for _, key := range config.AccessKeys(r.Access) {
+		fmt.Fprintf(&b, "# access.%s=%t\n", key, r.Access[key])
+	}
+	if c := r.Comparison; c != nil {
+		for _, key := range c.Differences {
+			fmt.Fprintf(&b, "# differs: %s current=%s\n", key, c.Current[key])
+		}
+	}
+	for _, key := range config.EnvVarKeys(r.Env) {
+		fmt.Fprintf(&b, "%s=%s\n", key, r.Env[key])
+	}
+	return b.String()
+}
+
+// currentEnvVars returns the compared keys as currently configured
+func currentEnvVars(hw *config.HardwareConfig) map[string]string {
+	hardwarePWM := "0"
+	if hw.HardwarePWM {
+		hardwarePWM = "1"
+	}
+	return map[string]string{
+		"BUTTON_CHIP":  hw.ButtonChip,
+		"BUTTON_LINE":  hw.ButtonLine,
+		"FAN_CHIP":     hw.FanChip,
+		"FAN_LINE":     hw.FanLine,
+		"FAN_POLARITY": string(hw.FanPolarity),
+		"HARDWARE_PWM": hardwarePWM,
+		"I2C_BUS":      os.Getenv("I2C_BUS"),
+	}
+}
+
+// envDifferences returns the compared keys whose values differ
+func envDifferences(current, detected map[string]string) []string {
+	differences := []string{}
+	for _, key := range comparedKeys {
+		if current[key] != detected[key] {
+			differences = append(differences, key)
+		}
+	}
+	return differences
+}
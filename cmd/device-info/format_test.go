@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+// goldenReports are rendered in every format and compared to testdata
+var goldenReports = map[string]config.DetectionReport{
+	"full": {
+		SchemaVersion: 1,
+		BoardType:     "rock5b",
+		Model:         `Radxa ROCK 5 Model B "rev 1.4"`,
+		Confidence:    90,
+		Env: map[string]string{
+			"ZZ_EXTRA":     "last",
+			"HARDWARE_PWM": "1",
+			"PWMCHIP":      "1",
+			"PWM_CHANNEL":  "0",
+			"BUTTON_LINE":  "14",
+			"BUTTON_CHIP":  "4",
+			"I2C_BUS":      "/dev/i2c-8",
+			"SDA":          "SDA",
+			"SCL":          "SCL",
+			"OLED_RESET":   "",
+			"FAN_POLARITY": "inverted",
+		},
+		Access: map[string]bool{
+			"i2c_bus":      true,
+			"gpio_chip":    true,
+			"hardware_pwm": false,
+		},
+		Verified: false,
+		Notes: []string{
+			"Detected from device tree compatible: radxa,rock-5b",
+			"pwmchip1 has no channel 0",
+		},
+		Comparison: &config.ConfigComparison{
+			Current: map[string]string{
+				"BUTTON_CHIP":  "4",
+				"BUTTON_LINE":  "14",
+				"FAN_CHIP":     "",
+				"FAN_LINE":     "",
+				"FAN_POLARITY": "normal",
+				"HARDWARE_PWM": "1",
+				"I2C_BUS":      "/dev/i2c-8",
+			},
+			Differences: []string{"FAN_POLARITY"},
+		},
+	},
+	"empty": {
+		SchemaVersion: 1,
+		BoardType:     "unknown-fallback-rpi5",
+		Env:           map[string]string{},
+		Access:        map[string]bool{},
+		Notes:         []string{},
+	},
+}
+
+func TestFormatGolden(t *testing.T) {
+	for name, report := range goldenReports {
+		for _, format := range []string{"json", "yaml", "env"} {
+			t.Run(name+"/"+format, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := writeReport(&buf, report, format); err != nil {
+					t.Fatal(err)
+				}
+
+				golden := filepath.Join("testdata", name+"."+format+".golden")
+				if *update {
+					if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := buf.String(); got != string(want) {
+					t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", golden, got, want)
+				}
+			})
+		}
+	}
+}
+
+// TestFormatYAMLKeyOrder checks that the YAML top level follows the JSON
+// schema, so the two outputs can't drift apart
+func TestFormatYAMLKeyOrder(t *testing.T) {
+	for name, report := range goldenReports {
+		data, err := json.Marshal(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := jsonTopLevelKeys(t, data)
+
+		var got []string
+		for _, line := range strings.Split(formatYAML(report), "\n") {
+			if line == "" || strings.HasPrefix(line, " ") {
+				continue
+			}
+			key, _, _ := strings.Cut(line, ":")
+			got = append(got, key)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: YAML keys = %v, JSON keys = %v", name, got, want)
+		}
+	}
+}
+
+// jsonTopLevelKeys returns the keys of a JSON object in document order
+func jsonTopLevelKeys(t *testing.T, data []byte) []string {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, token.(string))
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keys
+}
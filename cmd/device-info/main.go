@@ -4,28 +4,75 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/fan"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hostfs"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "probe" {
+		if err := runProbe(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "probe: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var (
 		showEnvVars = flag.Bool("env", false, "Show environment variables that should be set")
 		showExport  = flag.Bool("export", false, "Show export commands for detected environment variables")
 		verify      = flag.Bool("verify", false, "Verify hardware access with current configuration")
 		verbose     = flag.Bool("v", false, "Verbose output")
+		pwmBench    = flag.Bool("pwm-bench", false, "Measure software PWM accuracy against a fake pin")
+		pwmFreq     = flag.Float64("pwm-freq", 40, "Frequency (Hz) used by -pwm-bench")
+		format      = flag.String("format", "text", "Output format: text, json, yaml or env")
+		root        = flag.String("root", "/", "Inspect the procfs, sysfs and /dev tree below this directory")
 	)
 	flag.Parse()
+	hostfs.SetRoot(*root)
+
+	if !validFormat(*format) {
+		fmt.Fprintf(os.Stderr, "unknown format %q (expected text, json, yaml or env)\n", *format)
+		os.Exit(2)
+	}
+
+	if *pwmBench {
+		benchmarkSoftwarePWM(*pwmFreq)
+		return
+	}
 
 	// Perform device detection
 	device := config.DetectDevice()
 
+	if *format != "text" {
+		report := device.Report()
+		if *verify {
+			config.Load()
+			current := currentEnvVars(config.HWConfig)
+			report.Comparison = &config.ConfigComparison{
+				Current:     current,
+				Differences: envDifferences(current, report.Env),
+			}
+		}
+		if err := writeReport(os.Stdout, report, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write report: %v\n", err)
+			os.Exit(2)
+		}
+		if !report.Verified {
+			os.Exit(1)
+		}
+		return
+	}
+
 	if *showExport {
 		// Show export commands
 		fmt.Println("# Add these to your shell environment or /etc/rockpi-penta.env:")
 		envVars := device.GetRecommendedEnvVars()
-		for key, value := range envVars {
-			fmt.Printf("export %s=%s\n", key, value)
+		for _, key := range config.EnvVarKeys(envVars) {
+			fmt.Printf("export %s=%s\n", key, envVars[key])
 		}
 		return
 	}
@@ -33,8 +80,8 @@ func main() {
 	if *showEnvVars {
 		// Show just the environment variables
 		envVars := device.GetRecommendedEnvVars()
-		for key, value := range envVars {
-			fmt.Printf("%s=%s\n", key, value)
+		for _, key := range config.EnvVarKeys(envVars) {
+			fmt.Printf("%s=%s\n", key, envVars[key])
 		}
 		return
 	}
@@ -50,6 +97,7 @@ func main() {
 		fmt.Printf("Current Configuration:\n")
 		fmt.Printf("  BUTTON_CHIP=%s, BUTTON_LINE=%s\n", hwCfg.ButtonChip, hwCfg.ButtonLine)
 		fmt.Printf("  FAN_CHIP=%s, FAN_LINE=%s\n", hwCfg.FanChip, hwCfg.FanLine)
+		fmt.Printf("  FAN_POLARITY=%s\n", hwCfg.FanPolarity)
 		fmt.Printf("  HARDWARE_PWM=%t\n", hwCfg.HardwarePWM)
 		fmt.Printf("  I2C_BUS=%s\n", os.Getenv("I2C_BUS"))
 		fmt.Println()
@@ -58,9 +106,9 @@ func main() {
 		access := device.VerifyHardwareAccess()
 		fmt.Println("Hardware Access Test:")
 		allGood := true
-		for component, accessible := range access {
+		for _, component := range config.AccessKeys(access) {
 			status := "❌ FAIL"
-			if accessible {
+			if access[component] {
 				status = "✅ OK"
 			} else {
 				allGood = false
@@ -76,43 +124,25 @@ func main() {
 		}
 
 		// Compare with detected values
+		current := currentEnvVars(hwCfg)
 		detected := device.GetRecommendedEnvVars()
 		fmt.Println("\nConfiguration Comparison:")
-		differences := 0
-
-		if hwCfg.ButtonChip != detected["BUTTON_CHIP"] {
-			fmt.Printf("  BUTTON_CHIP: current=%s, detected=%s ⚠️\n", hwCfg.ButtonChip, detected["BUTTON_CHIP"])
-			differences++
-		}
-		if hwCfg.ButtonLine != detected["BUTTON_LINE"] {
-			fmt.Printf("  BUTTON_LINE: current=%s, detected=%s ⚠️\n", hwCfg.ButtonLine, detected["BUTTON_LINE"])
-			differences++
-		}
-		if hwCfg.FanChip != detected["FAN_CHIP"] {
-			fmt.Printf("  FAN_CHIP: current=%s, detected=%s ⚠️\n", hwCfg.FanChip, detected["FAN_CHIP"])
-			differences++
-		}
-		if hwCfg.FanLine != detected["FAN_LINE"] {
-			fmt.Printf("  FAN_LINE: current=%s, detected=%s ⚠️\n", hwCfg.FanLine, detected["FAN_LINE"])
-			differences++
-		}
-		hwPwmStr := "0"
-		if hwCfg.HardwarePWM {
-			hwPwmStr = "1"
-		}
-		if hwPwmStr != detected["HARDWARE_PWM"] {
-			fmt.Printf("  HARDWARE_PWM: current=%s, detected=%s ⚠️\n", hwPwmStr, detected["HARDWARE_PWM"])
-			differences++
+		differences := envDifferences(current, detected)
+		for _, key := range differences {
+			fmt.Printf("  %s: current=%s, detected=%s ⚠️\n", key, current[key], detected[key])
 		}
 
-		if differences == 0 {
+		if len(differences) == 0 {
 			fmt.Println("  ✅ Configuration matches detected values")
 		} else {
-			fmt.Printf("  ⚠️  %d configuration differences found\n", differences)
+			fmt.Printf("  ⚠️  %d configuration differences found\n", len(differences))
 			fmt.Println("     Consider updating your environment variables")
 		}
 
 		_ = cfg // Use cfg to avoid unused variable warning
+		if !allGood {
+			os.Exit(1)
+		}
 		return
 	}
 
@@ -124,7 +154,7 @@ func main() {
 
 		// Show current environment variables
 		fmt.Println("\nCurrent Environment Variables:")
-		envVars := []string{"BUTTON_CHIP", "BUTTON_LINE", "FAN_CHIP", "FAN_LINE", "HARDWARE_PWM", "I2C_BUS", "SDA", "SCL", "OLED_RESET"}
+		envVars := []string{"BUTTON_CHIP", "BUTTON_LINE", "FAN_CHIP", "FAN_LINE", "FAN_POLARITY", "HARDWARE_PWM", "PWMCHIP", "PWM_CHANNEL", "PWM_FREQUENCY", "FAN_TYPE", "I2C_BUS", "SDA", "SCL", "OLED_RESET"}
 		for _, envVar := range envVars {
 			if value := os.Getenv(envVar); value != "" {
 				fmt.Printf("  %s=%s\n", envVar, value)
@@ -137,11 +167,35 @@ func main() {
 		fmt.Println("\nGPIO Information:")
 		gpioInfo := config.ParseGPIOFromKernel()
 		if len(gpioInfo) > 0 {
-			for key, value := range gpioInfo {
-				fmt.Printf("  %s: %s\n", key, value)
+			keys := make([]string, 0, len(gpioInfo))
+			for key := range gpioInfo {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("  %s: %s\n", key, gpioInfo[key])
 			}
 		} else {
 			fmt.Println("  (GPIO debug information not available - requires root)")
 		}
 	}
 }
+
+// benchmarkSoftwarePWM runs each software PWM backend at a few duty cycles
+// against a fake pin and prints the achieved duty and edge jitter
+func benchmarkSoftwarePWM(frequency float64) {
+	fmt.Printf("=== Software PWM Benchmark (%.0f Hz) ===\n", frequency)
+	fmt.Printf("%-8s %7s %9s %8s %12s %12s\n", "backend", "target", "achieved", "periods", "mean jitter", "max jitter")
+
+	for _, backend := range []string{fan.SoftPWMPrecise, fan.SoftPWMTicker} {
+		for _, power := range []float64{10, 25, 50, 75, 90} {
+			stats, err := fan.MeasureSoftwarePWM(backend, frequency, power, 2*time.Second)
+			if err != nil {
+				fmt.Printf("%-8s %6.0f%% error: %v\n", backend, power, err)
+				continue
+			}
+			fmt.Printf("%-8s %6.0f%% %8.2f%% %8d %12s %12s\n", backend, power, stats.Duty, stats.Periods,
+				stats.MeanJitter.Round(time.Microsecond), stats.MaxJitter.Round(time.Microsecond))
+		}
+	}
+}
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"periph.io/x/conn/v3/gpio"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/fan"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/gpiocdev"
+)
+
+// Consumer label of the lines held while probing
+const probeConsumer = "rockpi-penta-probe"
+
+// I2C_SLAVE from linux/i2c-dev.h
+const i2cSlaveIoctl = 0x0703
+
+// SSD1306 addresses selectable on the OLED module; the daemon drives 0x3C
+var oledAddresses = []uint16{0x3C, 0x3D}
+
+// Keys the probe owns even when it leaves them out, so they are never
+// carried over from an existing env file
+var fanOutputKeys = map[string]bool{
+	"HARDWARE_PWM": true,
+	"PWMCHIP":      true,
+	"PWM_CHANNEL":  true,
+	"FAN_CHIP":     true,
+	"FAN_LINE":     true,
+}
+
+// fanCandidate is a GPIO line or PWM channel that may drive the fan
+type fanCandidate struct {
+	hardware bool
+	chip     string // GPIO chip, or PWM chip number with hardware PWM
+	line     string // GPIO line, or PWM channel with hardware PWM
+	source   string // Board profile the candidate comes from
+	detected bool   // Fan output of the detected board
+}
+
+func (c fanCandidate) String() string {
+	if c.hardware {
+		return fmt.Sprintf("pwmchip%s channel %s (%s)", c.chip, c.line, c.source)
+	}
+	return fmt.Sprintf("GPIO chip %s line %s (%s)", c.chip, c.line, c.source)
+}
+
+// prober walks the user through identifying the Penta HAT wiring
+type prober struct {
+	in          *bufio.Reader
+	device      *config.DeviceInfo
+	candidates  []*config.DeviceInfo // Detected board first, then every profile
+	env         map[string]string
+	spin        time.Duration
+	buttonWait  time.Duration
+	fanFound    bool
+	buttonFound bool
+}
+
+// runProbe implements the probe subcommand
+func runProbe(args []string) error {
+	flags := flag.NewFlagSet("probe", flag.ExitOnError)
+	output := flags.String("o", "/etc/rockpi-penta.env", "Environment file to write")
+	dryRun := flags.Bool("dry-run", false, "Print the environment file instead of writing it")
+	spin := flags.Duration("spin", 3*time.Second, "How long the fan is held off and on for each candidate")
+	buttonWait := flags.Duration("button-timeout", 15*time.Second, "How long to wait for a button press")
+	flags.Parse(args)
+
+	fmt.Println("=== Hardware Probe ===")
+	fmt.Println("Stop the service first so the GPIO lines are free: sudo systemctl stop rockpi-penta")
+	fmt.Println()
+
+	p := &prober{
+		in:         bufio.NewReader(os.Stdin),
+		device:     config.DetectDevice(),
+		spin:       *spin,
+		buttonWait: *buttonWait,
+	}
+	p.env = p.device.GetRecommendedEnvVars()
+	p.candidates = []*config.DeviceInfo{p.device}
+	if profiles, err := config.LoadBoardProfiles(); err == nil {
+		ids := make([]string, 0, len(profiles))
+		for id := range profiles {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			p.candidates = append(p.candidates, config.DeviceForProfile(profiles, id))
+		}
+	}
+	fmt.Printf("Detected board: %s (confidence: %d%%)\n\n", p.device.BoardType, p.device.Confidence)
+
+	p.probeOLED()
+	p.probeFan()
+	p.probeButton()
+
+	// Settings the probe doesn't produce, like PWM_FREQUENCY, are kept
+	if existing, err := readEnvFile(*output); err == nil {
+		if kept := mergeEnv(p.env, existing); len(kept) > 0 {
+			fmt.Printf("Keeping %s from %s\n\n", strings.Join(kept, ", "), *output)
+		}
+	}
+
+	if errs := validateEnv(p.env); len(errs) > 0 {
+		fmt.Println("The probed configuration is not usable:")
+		for _, err := range errs {
+			fmt.Printf("  - %v\n", err)
+		}
+		return fmt.Errorf("validation failed, %s not written", *output)
+	}
+
+	content := formatEnvFile(p.env)
+	fmt.Println("=== Probed Configuration ===")
+	fmt.Print(content)
+	fmt.Println()
+
+	if !p.fanFound || !p.buttonFound {
+		fmt.Println("⚠️  Some components were not confirmed, detected defaults are used for them.")
+	}
+	if *dryRun {
+		return nil
+	}
+	if !p.confirm(fmt.Sprintf("Write %s?", *output)) {
+		fmt.Println("Nothing written.")
+		return nil
+	}
+	if err := writeEnvFile(*output, content); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Wrote %s, restart the service to apply it: sudo systemctl restart rockpi-penta\n", *output)
+	return nil
+}
+
+// ask prints a question and returns the trimmed answer
+func (p *prober) ask(question string) string {
+	fmt.Print(question + " ")
+	answer, _ := p.in.ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+// confirm asks a yes/no question, defaulting to no
+func (p *prober) confirm(question string) bool {
+	answer := strings.ToLower(p.ask(question + " [y/N]"))
+	return answer == "y" || answer == "yes"
+}
+
+// probeOLED scans every I2C bus for an SSD1306
+func (p *prober) probeOLED() {
+	fmt.Println("--- OLED display ---")
+
+	paths, _ := filepath.Glob("/dev/i2c-*")
+	sort.Slice(paths, func(i, j int) bool {
+		return busNumber(paths[i]) < busNumber(paths[j])
+	})
+
+	var found, other []string
+	for _, path := range paths {
+		for _, addr := range oledAddresses {
+			present, note := probeI2CAddress(path, addr)
+			if !present {
+				continue
+			}
+			fmt.Printf("  Found device at 0x%02X on %s%s\n", addr, path, note)
+			if addr == oledAddresses[0] {
+				found = append(found, path)
+			} else {
+				other = append(other, path)
+			}
+		}
+	}
+
+	switch {
+	case containsPath(found, p.env["I2C_BUS"]):
+		fmt.Printf("  Using %s\n\n", p.env["I2C_BUS"])
+	case len(found) > 0:
+		p.env["I2C_BUS"] = found[0]
+		fmt.Printf("  Using %s\n\n", found[0])
+	case len(other) > 0:
+		p.env["I2C_BUS"] = other[0]
+		fmt.Printf("  ⚠️  Only found a display at 0x%02X, set the module to 0x%02X for the service to drive it\n\n", oledAddresses[1], oledAddresses[0])
+	default:
+		fmt.Printf("  ⚠️  No display found, keeping %s\n\n", p.env["I2C_BUS"])
+	}
+}
+
+// probeI2CAddress reports whether a device acknowledges a one byte read at
+// addr; an address claimed by a kernel driver counts as present
+func probeI2CAddress(path string, addr uint16) (bool, string) {
+	file, err := os.OpenFile(path, os.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return false, ""
+	}
+	defer file.Close()
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), i2cSlaveIoctl, uintptr(addr)); errno != 0 {
+		if errno == syscall.EBUSY {
+			return true, " (claimed by a kernel driver)"
+		}
+		return false, ""
+	}
+
+	var buf [1]byte
+	_, err = file.Read(buf[:])
+	return err == nil, ""
+}
+
+// fanCandidates returns the fan outputs of the detected board and every
+// board profile that exist on this system, without duplicates. The detected
+// board comes first, so a line it shares with other profiles counts as its own.
+func (p *prober) fanCandidates() []fanCandidate {
+	var candidates []fanCandidate
+	seen := make(map[string]bool)
+	for _, device := range p.candidates {
+		detected := device == p.device
+		c := fanCandidate{chip: device.FanChip, line: device.FanLine, source: device.BoardType, detected: detected}
+		if device.HardwarePWM {
+			c = fanCandidate{hardware: true, chip: device.PWMChip, line: strconv.Itoa(device.PWMChannel), source: device.BoardType, detected: detected}
+			if !pwmChannelExists(c.chip, device.PWMChannel) {
+				continue
+			}
+		} else {
+			info, offset, err := gpiocdev.FindLine(c.chip, c.line)
+			if err != nil {
+				continue
+			}
+			c.chip, c.line = info.Number(), strconv.Itoa(offset)
+		}
+
+		key := fmt.Sprintf("%t/%s/%s", c.hardware, c.chip, c.line)
+		if !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// probeFan drives each candidate off then on until the user sees the fan
+// react, which also tells the polarity. Lines of other board profiles may be
+// wired to something else on this board, so they are only driven when the
+// user agrees.
+func (p *prober) probeFan() {
+	fmt.Println("--- Fan ---")
+	fmt.Printf("Each candidate stops the fan for %s, then runs it at full speed for %s.\n", p.spin, p.spin)
+
+	for _, c := range p.fanCandidates() {
+		if !c.detected && !p.confirm(fmt.Sprintf("  %s is not the fan output of the detected board. Drive it as an output anyway?", c)) {
+			continue
+		}
+		fmt.Printf("  Trying %s\n", c)
+		polarity, ok, err := p.testFan(c)
+		if err != nil {
+			fmt.Printf("    skipped: %v\n", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		p.fanFound = true
+		p.env["FAN_POLARITY"] = string(polarity)
+		if c.hardware {
+			p.env["HARDWARE_PWM"] = "1"
+			p.env["PWMCHIP"] = c.chip
+			p.env["PWM_CHANNEL"] = c.line
+			delete(p.env, "FAN_CHIP")
+			delete(p.env, "FAN_LINE")
+		} else {
+			p.env["HARDWARE_PWM"] = "0"
+			p.env["FAN_CHIP"] = c.chip
+			p.env["FAN_LINE"] = c.line
+			delete(p.env, "PWMCHIP")
+			delete(p.env, "PWM_CHANNEL")
+		}
+		fmt.Printf("  ✅ Fan on %s, %s polarity (left at full speed)\n\n", c, polarity)
+		return
+	}
+
+	fmt.Println("  ⚠️  Fan not identified, keeping the detected settings")
+	fmt.Println()
+}
+
+// testFan runs the off/on sequence on a candidate with each polarity and
+// asks the user about it. Lines that turn out not to drive the fan are
+// switched back to inputs.
+func (p *prober) testFan(c fanCandidate) (config.FanPolarity, bool, error) {
+	for _, polarity := range []config.FanPolarity{config.FanPolarityInverted, config.FanPolarityNormal} {
+		out, release, err := openFanOutput(c, polarity)
+		if err != nil {
+			return "", false, err
+		}
+
+		out.SetDutyCycle(0)
+		time.Sleep(p.spin)
+		out.SetDutyCycle(100)
+		time.Sleep(p.spin)
+
+		if p.confirm(fmt.Sprintf("    Did the fan stop and then spin up (%s polarity)?", polarity)) {
+			out.Close()
+			return polarity, true, nil
+		}
+		release()
+	}
+	return "", false, nil
+}
+
+// openFanOutput opens a candidate as a fan output and returns a function
+// that undoes the probe on it
+func openFanOutput(c fanCandidate, polarity config.FanPolarity) (fan.PWMInterface, func(), error) {
+	if c.hardware {
+		// Only 0% and 100% are driven, so any period will do
+		channel, _ := strconv.Atoi(c.line)
+		settings := config.PWMSettings{Chip: c.chip, Channel: channel, Frequency: 1000}
+		pwm, err := fan.OpenHardwarePWM(settings, polarity)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pwm, func() { pwm.Disable() }, nil
+	}
+
+	info, offset, err := gpiocdev.FindLine(c.chip, c.line)
+	if err != nil {
+		return nil, nil, err
+	}
+	line, err := gpiocdev.Request(info, offset, probeConsumer)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := &steadyFan{line: line, polarity: polarity}
+	return out, func() { releaseLine(line) }, nil
+}
+
+// steadyFan drives a GPIO line fully on or off, enough to see the fan react
+type steadyFan struct {
+	line     *gpiocdev.Line
+	polarity config.FanPolarity
+}
+
+func (s *steadyFan) SetDutyCycle(power float64) error {
+	return s.line.Out(fan.NearestLevel(power, s.polarity))
+}
+
+func (s *steadyFan) Close() error {
+	return s.line.Close()
+}
+
+// probeButton watches the button lines of every candidate board and picks
+// the first one that changes while the user presses the button
+func (p *prober) probeButton() {
+	fmt.Println("--- Button ---")
+
+	type watched struct {
+		line *gpiocdev.Line
+		chip string
+	}
+	var lines []watched
+	seen := make(map[string]bool)
+	for _, device := range p.candidates {
+		info, offset, err := gpiocdev.FindLine(device.ButtonChip, device.ButtonLine)
+		if err != nil {
+			continue
+		}
+		key := fmt.Sprintf("%s/%d", info.Name, offset)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		line, err := gpiocdev.Request(info, offset, probeConsumer)
+		if err != nil {
+			fmt.Printf("  Skipping %s line %d: %v\n", info.Name, offset, err)
+			continue
+		}
+		if err := line.In(gpio.PullUp, gpio.BothEdges); err != nil {
+			fmt.Printf("  Skipping %s: %v\n", line, err)
+			releaseLine(line)
+			continue
+		}
+		lines = append(lines, watched{line: line, chip: info.Number()})
+	}
+	defer func() {
+		for _, w := range lines {
+			releaseLine(w.line)
+		}
+	}()
+
+	if len(lines) == 0 {
+		fmt.Println("  ⚠️  No candidate button lines available, keeping the detected settings")
+		fmt.Println()
+		return
+	}
+
+	fmt.Printf("Press and release the button within %s...\n", p.buttonWait)
+	pressed := make(chan watched, len(lines))
+	done := make(chan struct{})
+	defer close(done)
+	for _, w := range lines {
+		go func(w watched) {
+			initial := w.line.Read()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				start := time.Now()
+				edge := w.line.WaitForEdge(200 * time.Millisecond)
+				if edge || w.line.Read() != initial {
+					pressed <- w
+					return
+				}
+				if time.Since(start) < time.Millisecond {
+					// No edge detection on this line, poll it
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+		}(w)
+	}
+
+	select {
+	case w := <-pressed:
+		p.buttonFound = true
+		p.env["BUTTON_CHIP"] = w.chip
+		p.env["BUTTON_LINE"] = strconv.Itoa(w.line.Number())
+		fmt.Printf("  ✅ Button on %s\n\n", w.line)
+	case <-time.After(p.buttonWait):
+		fmt.Println("  ⚠️  No button press seen, keeping the detected settings")
+		fmt.Println()
+	}
+}
+
+// releaseLine puts a probed line back the way it was found and releases it
+func releaseLine(line *gpiocdev.Line) {
+	if err := line.Restore(); err != nil {
+		fmt.Printf("  ⚠️  Failed to restore %s: %v\n", line, err)
+	}
+	line.Close()
+}
+
+// validateEnv checks that every configured device exists on this system
+func validateEnv(env map[string]string) []error {
+	var errs []error
+
+	if _, err := os.Stat(env["I2C_BUS"]); err != nil {
+		errs = append(errs, fmt.Errorf("I2C_BUS: %v", err))
+	}
+	if _, _, err := gpiocdev.FindLine(env["BUTTON_CHIP"], env["BUTTON_LINE"]); err != nil {
+		errs = append(errs, fmt.Errorf("BUTTON_CHIP/BUTTON_LINE: %v", err))
+	}
+	if _, err := config.ParseFanPolarity(env["FAN_POLARITY"]); err != nil {
+		errs = append(errs, fmt.Errorf("FAN_POLARITY: %v", err))
+	}
+
+	if env["HARDWARE_PWM"] == "1" {
+		channel, err := strconv.Atoi(env["PWM_CHANNEL"])
+		if err != nil || !pwmChannelExists(env["PWMCHIP"], channel) {
+			errs = append(errs, fmt.Errorf("PWMCHIP/PWM_CHANNEL: no channel %s on pwmchip%s", env["PWM_CHANNEL"], env["PWMCHIP"]))
+		}
+	} else if _, _, err := gpiocdev.FindLine(env["FAN_CHIP"], env["FAN_LINE"]); err != nil {
+		errs = append(errs, fmt.Errorf("FAN_CHIP/FAN_LINE: %v", err))
+	}
+
+	return errs
+}
+
+// formatEnvFile renders the env file with the keys in a fixed order
+func formatEnvFile(env map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by rockpi-penta-device-info probe on %s\n", time.Now().Format("2006-01-02 15:04"))
+	for _, key := range config.EnvVarKeys(env) {
+		if value := env[key]; value != "" {
+			fmt.Fprintf(&b, "%s=%s\n", key, value)
+		}
+	}
+	return b.String()
+}
+
+// readEnvFile parses the KEY=VALUE lines of an env file, skipping comments
+func readEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			env[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return env, nil
+}
+
+// mergeEnv adds the keys of an existing env file that the probe doesn't
+// manage to env and returns the sorted keys it added
+func mergeEnv(env, existing map[string]string) []string {
+	var kept []string
+	for key, value := range existing {
+		if _, ok := env[key]; ok || fanOutputKeys[key] {
+			continue
+		}
+		env[key] = value
+		kept = append(kept, key)
+	}
+	sort.Strings(kept)
+	return kept
+}
+
+// writeEnvFile replaces path atomically, keeping the previous file as .bak
+func writeEnvFile(path, content string) error {
+	if old, err := os.ReadFile(path); err == nil {
+		if err := os.WriteFile(path+".bak", old, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %v", path, err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+	return nil
+}
+
+// pwmChannelExists reports whether pwmchip<chip> has the channel
+func pwmChannelExists(chip string, channel int) bool {
+	data, err := os.ReadFile(fmt.Sprintf("/sys/class/pwm/pwmchip%s/npwm", chip))
+	if err != nil {
+		return false
+	}
+	npwm, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return err == nil && channel >= 0 && channel < npwm
+}
+
+func busNumber(path string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "i2c-"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func containsPath(paths []string, target string) bool {
+	for _, path := range paths {
+		if path == target {
+			return true
+		}
+	}
+	return false
+}
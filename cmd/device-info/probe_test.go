@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rockpi-penta.env")
+	existing := `# Generated by rockpi-penta-device-info probe
+I2C_BUS=/dev/i2c-1
+FAN_CHIP=0
+FAN_LINE=13
+PWM_FREQUENCY=25000
+
+FAN_TYPE = 4pin
+`
+	if err := os.WriteFile(path, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := readEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The probe switched to hardware PWM, the old fan line must not come back
+	env := map[string]string{
+		"I2C_BUS":      "/dev/i2c-7",
+		"HARDWARE_PWM": "1",
+		"PWMCHIP":      "1",
+		"PWM_CHANNEL":  "0",
+	}
+	kept := mergeEnv(env, old)
+
+	if want := []string{"FAN_TYPE", "PWM_FREQUENCY"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept = %v, want %v", kept, want)
+	}
+	want := map[string]string{
+		"I2C_BUS":       "/dev/i2c-7",
+		"HARDWARE_PWM":  "1",
+		"PWMCHIP":       "1",
+		"PWM_CHANNEL":   "0",
+		"PWM_FREQUENCY": "25000",
+		"FAN_TYPE":      "4pin",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("env = %v, want %v", env, want)
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"context"
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/button"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/disk"
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/fan"
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/oled"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/menu"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/state"
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/sysinfo"
 )
 
@@ -21,7 +24,20 @@ type Application struct {
 	fanController    *fan.Controller
 	oledController   *oled.Controller
 	buttonController *button.Controller
+	diskController   *disk.Controller
 	sysInfo          *sysinfo.SystemInfo
+	menu             *menu.Menu
+	menuTimer        *time.Timer
+	menuGeneration   int
+	menuHoldExit     bool // The menu was left with a hold that may still repeat
+	menuMutex        sync.Mutex
+	confirm          *pendingConfirm
+	confirmMutex     sync.Mutex
+	runningCommands  map[string]bool
+	commandMutex     sync.Mutex
+	savedState       state.State
+	stateFrozen      bool // A reboot or poweroff is in flight, don't save
+	stateMutex       sync.Mutex
 	ctx              context.Context
 	cancel           context.CancelFunc
 	wg               sync.WaitGroup
@@ -36,8 +52,11 @@ func main() {
 	log.Printf("Configuration loaded: %s", cfg)
 
 	// Create application
-	app := &Application{}
+	app := &Application{
+		runningCommands: make(map[string]bool),
+	}
 	app.ctx, app.cancel = context.WithCancel(context.Background())
+	defer app.recoverPanic()
 
 	// Initialize components
 	if err := app.initialize(); err != nil {
@@ -75,7 +94,9 @@ func (app *Application) initialize() error {
 	// Initialize hardware controllers
 	app.fanController = fan.GetInstance()
 	app.buttonController = button.GetInstance()
+	app.diskController = disk.GetInstance()
 	app.oledController = oled.GetInstance()
+	app.menu = menu.New(menu.DefaultItems())
 
 	// Try to initialize OLED (it might not be available)
 	if err := app.oledController.Initialize(); err != nil {
@@ -107,6 +128,11 @@ func (app *Application) start() error {
 		return err
 	}
 
+	// Start disk power management
+	if err := app.diskController.Start(); err != nil {
+		log.Printf("Disk power controller not started: %v", err)
+	}
+
 	// Start OLED if available
 	if app.hasOLED {
 		if err := app.oledController.Start(); err != nil {
@@ -132,11 +158,25 @@ func (app *Application) start() error {
 	app.wg.Add(1)
 	go app.systemInfoUpdater()
 
+	// Start overheat protection
+	app.wg.Add(1)
+	go app.criticalTempMonitor()
+
+	// Manual fan override via SIGUSR1/SIGUSR2
+	app.wg.Add(1)
+	go app.overrideSignalHandler()
+
+	// Restore and persist the runtime state
+	app.restoreState()
+	app.wg.Add(1)
+	go app.stateSaver()
+
 	return nil
 }
 
 func (app *Application) handleButtonEvents() {
 	defer app.wg.Done()
+	defer app.recoverPanic()
 
 	eventCh := app.buttonController.GetEventChannel()
 
@@ -145,6 +185,22 @@ func (app *Application) handleButtonEvents() {
 		case <-app.ctx.Done():
 			return
 		case event := <-eventCh:
+			// The first press on a blanked display only wakes it up
+			if app.hasOLED && app.oledController.Wake() {
+				log.Printf("Button event: %s -> display woken up", event)
+				continue
+			}
+
+			// While the menu is open the button navigates it
+			if app.handleMenuEvent(event) {
+				continue
+			}
+
+			// A pending reboot/poweroff consumes the next event
+			if app.handleConfirmEvent(event) {
+				continue
+			}
+
 			action := config.GlobalConfig.GetKeyAction(event)
 			log.Printf("Button event: %s -> action: %s", event, action)
 			
@@ -159,16 +215,18 @@ func (app *Application) handleButtonEvents() {
 				} else {
 					log.Println("Fan disabled")
 				}
-			case "reboot":
-				log.Println("Reboot requested via button")
-				app.executeSystemCommand("reboot")
-			case "poweroff":
-				log.Println("Poweroff requested via button")
-				app.executeSystemCommand("poweroff")
+			case "menu":
+				app.openMenu()
+			case "reboot", "poweroff":
+				app.requestConfirmedAction(event, action)
 			case "none":
 				// Do nothing
 			default:
-				log.Printf("Unknown action: %s", action)
+				if strings.HasPrefix(action, execPrefix) {
+					app.runCommandAction(strings.TrimPrefix(action, execPrefix))
+				} else {
+					log.Printf("Unknown action: %s", action)
+				}
 			}
 		}
 	}
@@ -176,6 +234,7 @@ func (app *Application) handleButtonEvents() {
 
 func (app *Application) systemInfoUpdater() {
 	defer app.wg.Done()
+	defer app.recoverPanic()
 
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
@@ -196,18 +255,16 @@ func (app *Application) systemInfoUpdater() {
 	}
 }
 
-func (app *Application) executeSystemCommand(cmd string) {
-	// Execute system command in a goroutine to avoid blocking
-	go func() {
-		log.Printf("Executing system command: %s", cmd)
-		
-		// Give some time for logging to complete
-		time.Sleep(1 * time.Second)
-		
-		if err := exec.Command("sudo", cmd).Run(); err != nil {
-			log.Printf("Failed to execute %s: %v", cmd, err)
+// recoverPanic puts the fan in its safe state before letting a panic crash
+// the service, so the disks are never left without cooling
+func (app *Application) recoverPanic() {
+	if r := recover(); r != nil {
+		log.Printf("Panic: %v, setting fan to safe duty", r)
+		if app.fanController != nil {
+			app.fanController.FailSafe()
 		}
-	}()
+		panic(r)
+	}
 }
 
 func (app *Application) shutdown() {
@@ -216,6 +273,10 @@ func (app *Application) shutdown() {
 	// Cancel context to stop goroutines
 	app.cancel()
 
+	// Keep the runtime state for the next start, unless a power action
+	// already saved it before changing the fan
+	app.saveState()
+
 	// Stop hardware controllers
 	if app.fanController != nil {
 		app.fanController.Stop()
@@ -225,6 +286,10 @@ func (app *Application) shutdown() {
 		app.buttonController.Stop()
 	}
 
+	if app.diskController != nil {
+		app.diskController.Stop()
+	}
+
 	if app.oledController != nil {
 		app.oledController.Stop()
 	}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/fan"
+)
+
+// menuTimeout closes the menu when no button event arrives in time
+const menuTimeout = 30 * time.Second
+
+// openMenu enters menu mode and shows the item list
+func (app *Application) openMenu() {
+	app.menuMutex.Lock()
+	defer app.menuMutex.Unlock()
+
+	app.menu.Open()
+	app.resetMenuTimer()
+	app.renderMenu()
+	log.Println("Menu opened")
+}
+
+// closeMenu leaves menu mode and returns to the information pages
+func (app *Application) closeMenu() {
+	app.menuMutex.Lock()
+	defer app.menuMutex.Unlock()
+	app.closeMenuLocked()
+}
+
+// closeMenuLocked is closeMenu for callers holding menuMutex
+func (app *Application) closeMenuLocked() {
+	if !app.menu.IsOpen() {
+		return
+	}
+	app.menu.Close()
+	app.stopMenuTimer()
+	app.oledController.ClearOverlay()
+	log.Println("Menu closed")
+}
+
+// isMenuOpen reports whether the menu is shown
+func (app *Application) isMenuOpen() bool {
+	app.menuMutex.Lock()
+	defer app.menuMutex.Unlock()
+	return app.menu.IsOpen()
+}
+
+// resetMenuTimer restarts the inactivity timeout of the menu. A timer that
+// already fired for an earlier generation does nothing. Caller holds menuMutex.
+func (app *Application) resetMenuTimer() {
+	app.stopMenuTimer()
+	generation := app.menuGeneration
+	app.menuTimer = time.AfterFunc(menuTimeout, func() {
+		app.menuMutex.Lock()
+		defer app.menuMutex.Unlock()
+		if app.menuGeneration == generation {
+			app.closeMenuLocked()
+		}
+	})
+}
+
+// stopMenuTimer cancels the inactivity timeout, caller holds menuMutex
+func (app *Application) stopMenuTimer() {
+	app.menuGeneration++
+	if app.menuTimer != nil {
+		app.menuTimer.Stop()
+	}
+}
+
+// renderMenu draws the current menu state on the OLED
+func (app *Application) renderMenu() {
+	app.oledController.ShowLines(app.menu.Lines())
+}
+
+// handleMenuEvent handles a button event while the menu is open:
+// click moves the cursor (or cancels a confirm step), double-click selects
+// and long press leaves the menu. It returns false if the menu is closed.
+func (app *Application) handleMenuEvent(event string) bool {
+	app.menuMutex.Lock()
+	defer app.menuMutex.Unlock()
+
+	if !app.menu.IsOpen() {
+		// Swallow the rest of the hold that closed the menu
+		if event == "hold" && app.menuHoldExit {
+			return true
+		}
+		app.menuHoldExit = false
+		return false
+	}
+	app.resetMenuTimer()
+
+	// Any event dismisses an information screen
+	if app.menu.DismissMessage() {
+		app.renderMenu()
+		return true
+	}
+
+	switch event {
+	case "click":
+		if !app.menu.Cancel() {
+			app.menu.Next()
+		}
+	case "twice":
+		if item := app.menu.Select(); item != nil {
+			log.Printf("Menu item selected: %s", item.Label)
+			app.runMenuAction(item.Action)
+			return true
+		}
+	case "press":
+		app.closeMenuLocked()
+		return true
+	case "hold":
+		// A bound hold replaces press
+		app.closeMenuLocked()
+		app.menuHoldExit = true
+		return true
+	}
+
+	app.renderMenu()
+	return true
+}
+
+// runMenuAction executes the action of a selected menu item, caller holds
+// menuMutex
+func (app *Application) runMenuAction(action string) {
+	switch action {
+	case "fan-auto":
+		app.fanController.SetMode(fan.ModeAuto)
+		app.closeMenuLocked()
+	case "fan-off":
+		app.fanController.SetMode(fan.ModeOff)
+		app.closeMenuLocked()
+	case "fan-full":
+		app.fanController.SetMode(fan.ModeFull)
+		app.closeMenuLocked()
+	case "fan-override":
+		app.menu.ShowMessage(app.toggleFanOverride())
+		app.renderMenu()
+	case "display-off":
+		app.closeMenuLocked()
+		app.oledController.Blank()
+	case "network":
+		app.menu.ShowMessage(app.sysInfo.GetNetworkDetails())
+		app.renderMenu()
+	case "reboot", "poweroff":
+		if err := app.powerAction(action); err != nil {
+			log.Printf("Refusing %s: %v", action, err)
+			app.menu.ShowMessage([]string{"Refused " + action, err.Error()})
+			app.renderMenu()
+		} else {
+			// Leave the shutdown message on screen instead of the pages
+			app.menu.Close()
+			app.stopMenuTimer()
+		}
+	default:
+		app.closeMenuLocked()
+	}
+}
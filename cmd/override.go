@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+// toggleFanOverride starts the configured manual fan override, or clears it
+// when one is active. Returns the status lines to show.
+func (app *Application) toggleFanOverride() []string {
+	if _, _, ok := app.fanController.GetOverride(); ok {
+		app.fanController.ClearOverride()
+		return []string{"Fan override off", "Automatic control"}
+	}
+
+	app.startFanOverride()
+	status, _ := app.fanController.FormatOverride()
+	return []string{"Fan override on", status}
+}
+
+// startFanOverride runs the fan at [fan] manual-duty for manual-time seconds
+func (app *Application) startFanOverride() {
+	cfg := config.GlobalConfig.Fan
+	app.fanController.SetOverride(cfg.ManualDuty, time.Duration(cfg.ManualTime)*time.Second)
+}
+
+// overrideSignalHandler starts the manual fan override on SIGUSR1 and
+// clears it on SIGUSR2
+func (app *Application) overrideSignalHandler() {
+	defer app.wg.Done()
+	defer app.recoverPanic()
+
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signalCh)
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case sig := <-signalCh:
+			log.Printf("Received signal %v", sig)
+			if sig == syscall.SIGUSR1 {
+				app.startFanOverride()
+			} else {
+				app.fanController.ClearOverride()
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log"
+	"syscall"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/power"
+)
+
+// executeSystemCommand runs the pre-shutdown sequence and asks systemd to
+// reboot or power off. The outcome is delivered on the returned channel.
+func (app *Application) executeSystemCommand(action power.Action) <-chan error {
+	result := make(chan error, 1)
+
+	go func() {
+		log.Printf("Executing system command: %s", action)
+
+		// Persist the state as the user left it before the sequence changes the fan
+		app.freezeState()
+
+		wasForced := app.prepareShutdown(action)
+		err := power.Execute(action)
+		if err != nil {
+			// The system stays up, undo what the sequence changed
+			app.fanController.SetForcedFull(wasForced)
+			app.thawState()
+		}
+		result <- err
+	}()
+
+	return result
+}
+
+// prepareShutdown shows the shutdown message, sets the fan to a safe state,
+// flushes filesystems and spins down the SATA disks before a poweroff.
+// It returns whether the fan was already forced to full speed, to restore
+// if the request fails.
+func (app *Application) prepareShutdown(action power.Action) bool {
+	if app.hasOLED {
+		if action == power.Reboot {
+			app.oledController.ShowLines([]string{"Rebooting..."})
+		} else {
+			app.oledController.ShowLines([]string{"Shutting down..."})
+		}
+	}
+
+	// Full speed keeps everything cool while services stop. Forcing it
+	// leaves the mode and override untouched.
+	wasForced := app.fanController.IsForcedFull()
+	app.fanController.SetForcedFull(true)
+
+	syscall.Sync()
+
+	// Disks spin back up on the next boot, so only park them before a poweroff
+	if action == power.PowerOff {
+		for _, device := range config.GlobalConfig.GetDiskDevices() {
+			if err := app.diskController.Standby(device); err != nil {
+				log.Printf("Failed to spin down %s: %v", device, err)
+			} else {
+				log.Printf("Spun down %s", device)
+			}
+		}
+	}
+
+	return wasForced
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/fan"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/state"
+)
+
+// stateSaveInterval is how often the runtime state is checked for changes
+const stateSaveInterval = 5 * time.Second
+
+// captureState returns the current runtime state
+func (app *Application) captureState() state.State {
+	s := state.State{
+		FanEnabled: config.GlobalConfig.IsRunning(),
+		FanFull:    app.fanController.GetMode() == fan.ModeFull,
+	}
+
+	if power, until, ok := app.fanController.GetOverrideUntil(); ok {
+		s.Override = &state.Override{Duty: power, Until: until}
+	}
+
+	if app.hasOLED {
+		// Pages rotating by themselves aren't worth a write every few seconds
+		if !config.GlobalConfig.Slider.Auto {
+			s.Page = app.oledController.GetPage()
+		}
+		s.DisplayOff = app.oledController.IsDisplayOff()
+	}
+
+	return s
+}
+
+// restoreState applies the state saved by the previous run. The display
+// state is applied last, so call it once the controllers are started.
+func (app *Application) restoreState() {
+	s, err := state.Load(state.DefaultPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to restore runtime state: %v", err)
+		}
+		app.savedState = app.captureState()
+		return
+	}
+
+	config.GlobalConfig.SetRunning(s.FanEnabled)
+	if s.FanEnabled && s.FanFull {
+		// Before the override, which SetMode clears
+		app.fanController.SetMode(fan.ModeFull)
+	}
+	if s.Override != nil {
+		if remaining := time.Until(s.Override.Until); remaining > 0 {
+			app.fanController.SetOverride(s.Override.Duty, remaining)
+		}
+	}
+	if app.hasOLED {
+		if !config.GlobalConfig.Slider.Auto {
+			app.oledController.SetPage(s.Page)
+		}
+		if s.DisplayOff {
+			app.oledController.Blank()
+		}
+	}
+
+	log.Printf("Runtime state restored (fan: %s, display off: %t)", app.fanController.GetMode(), s.DisplayOff)
+	app.savedState = app.captureState()
+}
+
+// saveState writes the runtime state if it changed since the last write.
+// Nothing is written while the state is frozen.
+func (app *Application) saveState() {
+	app.stateMutex.Lock()
+	defer app.stateMutex.Unlock()
+
+	if !app.stateFrozen {
+		app.saveStateLocked()
+	}
+}
+
+// freezeState saves the runtime state and stops further saves, so what the
+// shutdown sequence changes isn't restored on the next start
+func (app *Application) freezeState() {
+	app.stateMutex.Lock()
+	defer app.stateMutex.Unlock()
+
+	if !app.stateFrozen {
+		app.saveStateLocked()
+		app.stateFrozen = true
+	}
+}
+
+// thawState resumes saving the runtime state after a failed power action
+func (app *Application) thawState() {
+	app.stateMutex.Lock()
+	defer app.stateMutex.Unlock()
+	app.stateFrozen = false
+}
+
+// saveStateLocked writes the state if it changed, caller holds stateMutex
+func (app *Application) saveStateLocked() {
+	s := app.captureState()
+	if s.Equal(app.savedState) {
+		return
+	}
+
+	if err := state.Save(state.DefaultPath, s); err != nil {
+		log.Printf("Failed to save runtime state: %v", err)
+		return
+	}
+	app.savedState = s
+}
+
+// stateSaver persists the runtime state shortly after it changes
+func (app *Application) stateSaver() {
+	defer app.wg.Done()
+	defer app.recoverPanic()
+
+	ticker := time.NewTicker(stateSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-ticker.C:
+			app.saveState()
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+// maxOutput caps how much command output is kept for logging
+const maxOutput = 64 * 1024
+
+// Result describes a finished command
+type Result struct {
+	ExitCode int
+	Output   string
+	Duration time.Duration
+	TimedOut bool
+	Err      error // Set when the command could not be started or did not exit cleanly
+}
+
+// Success returns whether the command exited with status 0
+func (r *Result) Success() bool {
+	return r.Err == nil && r.ExitCode == 0
+}
+
+// Summary returns a short status like "OK", "exit 2" or "timeout"
+func (r *Result) Summary() string {
+	switch {
+	case r.TimedOut:
+		return "timeout"
+	case r.ExitCode > 0:
+		return fmt.Sprintf("exit %d", r.ExitCode)
+	case r.Err != nil:
+		return "failed"
+	default:
+		return "OK"
+	}
+}
+
+// LastLine returns the last non-empty line of the output
+func (r *Result) LastLine() string {
+	lines := strings.Split(strings.TrimSpace(r.Output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// Run executes a configured command action, killing its whole process group
+// when the timeout expires or ctx is cancelled
+func Run(ctx context.Context, action *config.CommandAction) *Result {
+	result := &Result{ExitCode: -1}
+
+	ctx, cancel := context.WithTimeout(ctx, action.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, action.Args[0], action.Args[1:]...)
+	cmd.Env = append(os.Environ(), action.Env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// Negative pid signals the process group so children die too
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+
+	if action.User != "" {
+		credential, home, err := lookupCredential(action.User)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		cmd.SysProcAttr.Credential = credential
+		cmd.Dir = home
+		cmd.Env = append(cmd.Env, "HOME="+home, "USER="+action.User, "LOGNAME="+action.User)
+	}
+
+	var output limitedBuffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	result.Output = output.String()
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.TimedOut = true
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.ExitCode = 0
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		if result.TimedOut {
+			result.Err = fmt.Errorf("timed out after %s", action.Timeout)
+		} else if ctx.Err() != nil {
+			// Killed because the caller cancelled, e.g. on shutdown
+			result.Err = ctx.Err()
+		}
+	default:
+		result.Err = err
+	}
+
+	return result
+}
+
+// lookupCredential resolves a user name to the credential used to run the command
+func lookupCredential(name string) (*syscall.Credential, string, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("unknown user %s: %v", name, err)
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid uid for %s: %v", name, err)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid gid for %s: %v", name, err)
+	}
+
+	credential := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	if groupIDs, err := u.GroupIds(); err == nil {
+		for _, id := range groupIDs {
+			if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+				credential.Groups = append(credential.Groups, uint32(g))
+			}
+		}
+	}
+
+	return credential, u.HomeDir, nil
+}
+
+// limitedBuffer keeps the first maxOutput bytes written to it
+type limitedBuffer struct {
+	buf bytes.Buffer
+}
+
+// Write stores as much of p as fits and reports it all as written
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := maxOutput - b.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buf.Write(p[:remaining])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// String returns the captured output
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
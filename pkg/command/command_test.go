@@ -0,0 +1,154 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+// shell builds an action running script with sh
+func shell(script string, timeout time.Duration) *config.CommandAction {
+	return &config.CommandAction{
+		Name:    "test",
+		Args:    []string{"sh", "-c", script},
+		Timeout: timeout,
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   *config.CommandAction
+		exitCode int
+		summary  string
+		success  bool
+		timedOut bool
+		output   string
+	}{
+		{
+			name:     "success",
+			action:   shell("echo first; echo last", 5*time.Second),
+			exitCode: 0,
+			summary:  "OK",
+			success:  true,
+			output:   "first\nlast\n",
+		},
+		{
+			name:     "exit code",
+			action:   shell("echo oops >&2; exit 3", 5*time.Second),
+			exitCode: 3,
+			summary:  "exit 3",
+			output:   "oops\n",
+		},
+		{
+			name: "environment",
+			action: &config.CommandAction{
+				Args:    []string{"sh", "-c", `echo "$ROCKPI_TEST"`},
+				Timeout: 5 * time.Second,
+				Env:     []string{"ROCKPI_TEST=hello"},
+			},
+			exitCode: 0,
+			summary:  "OK",
+			success:  true,
+			output:   "hello\n",
+		},
+		{
+			name:     "timeout kills the process group",
+			action:   shell("sleep 30 & sleep 30", 200*time.Millisecond),
+			exitCode: -1,
+			summary:  "timeout",
+			timedOut: true,
+		},
+		{
+			name: "missing command",
+			action: &config.CommandAction{
+				Args:    []string{"/nonexistent/rockpi-test"},
+				Timeout: 5 * time.Second,
+			},
+			exitCode: -1,
+			summary:  "failed",
+		},
+		{
+			name: "unknown user",
+			action: &config.CommandAction{
+				Args:    []string{"true"},
+				User:    "rockpi-no-such-user",
+				Timeout: 5 * time.Second,
+			},
+			exitCode: -1,
+			summary:  "failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Run(context.Background(), tt.action)
+
+			if result.ExitCode != tt.exitCode {
+				t.Errorf("ExitCode = %d, want %d (err %v)", result.ExitCode, tt.exitCode, result.Err)
+			}
+			if got := result.Summary(); got != tt.summary {
+				t.Errorf("Summary() = %q, want %q", got, tt.summary)
+			}
+			if got := result.Success(); got != tt.success {
+				t.Errorf("Success() = %t, want %t", got, tt.success)
+			}
+			if result.TimedOut != tt.timedOut {
+				t.Errorf("TimedOut = %t, want %t", result.TimedOut, tt.timedOut)
+			}
+			if tt.output != "" && result.Output != tt.output {
+				t.Errorf("Output = %q, want %q", result.Output, tt.output)
+			}
+			if result.Duration > 5*time.Second {
+				t.Errorf("took %s, the process group wasn't killed", result.Duration)
+			}
+		})
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	result := Run(ctx, shell("sleep 30", time.Minute))
+	if !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("Err = %v, want context.Canceled", result.Err)
+	}
+	if result.TimedOut {
+		t.Error("cancellation reported as timeout")
+	}
+	if result.Success() || result.Summary() == "OK" {
+		t.Errorf("cancelled command reported %q, success %t", result.Summary(), result.Success())
+	}
+}
+
+func TestRunOutputCap(t *testing.T) {
+	result := Run(context.Background(), shell("head -c 200000 /dev/zero | tr '\\0' x", 5*time.Second))
+	if !result.Success() {
+		t.Fatalf("command failed: %s %v", result.Summary(), result.Err)
+	}
+	if len(result.Output) != maxOutput {
+		t.Errorf("kept %d bytes of output, want %d", len(result.Output), maxOutput)
+	}
+}
+
+func TestLastLine(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"", ""},
+		{"one\n", "one"},
+		{"one\ntwo\n\n", "two"},
+		{"  padded  \n", "padded"},
+	}
+	for _, tt := range tests {
+		r := &Result{Output: tt.output}
+		if got := r.LastLine(); got != tt.want {
+			t.Errorf("LastLine(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
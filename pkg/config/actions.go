@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// CommandAction is a named shell command defined in the [actions] section
+// and bound to a gesture as "exec:<name>"
+type CommandAction struct {
+	Name    string
+	Args    []string
+	User    string
+	Timeout time.Duration
+	Env     []string
+	Show    bool
+}
+
+// defaultCommandTimeout bounds commands that don't set a timeout
+const defaultCommandTimeout = 60 * time.Second
+
+// loadCommandActions parses the [actions] section. Each action is a key holding
+// the command line, with optional <name>.user, <name>.timeout (seconds),
+// <name>.env (comma separated KEY=VALUE pairs) and <name>.show keys.
+func loadCommandActions(section *ini.Section) (map[string]*CommandAction, error) {
+	actions := make(map[string]*CommandAction)
+
+	// Command lines first so options can refer to them
+	for _, key := range section.Keys() {
+		if strings.Contains(key.Name(), ".") {
+			continue
+		}
+		args, err := SplitCommandLine(key.String())
+		if err != nil {
+			return nil, fmt.Errorf("action %s: %v", key.Name(), err)
+		}
+		if len(args) == 0 {
+			return nil, fmt.Errorf("action %s: empty command", key.Name())
+		}
+		actions[key.Name()] = &CommandAction{
+			Name:    key.Name(),
+			Args:    args,
+			Timeout: defaultCommandTimeout,
+		}
+	}
+
+	for _, key := range section.Keys() {
+		name, option, found := strings.Cut(key.Name(), ".")
+		if !found {
+			continue
+		}
+		action, exists := actions[name]
+		if !exists {
+			return nil, fmt.Errorf("option %s for undefined action %s", key.Name(), name)
+		}
+
+		value := strings.TrimSpace(key.String())
+		switch option {
+		case "user":
+			action.User = value
+		case "timeout":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds <= 0 {
+				return nil, fmt.Errorf("action %s: invalid timeout %q", name, value)
+			}
+			action.Timeout = time.Duration(seconds * float64(time.Second))
+		case "env":
+			for _, pair := range strings.Split(value, ",") {
+				pair = strings.TrimSpace(pair)
+				if pair == "" {
+					continue
+				}
+				if !strings.Contains(pair, "=") {
+					return nil, fmt.Errorf("action %s: invalid env entry %q", name, pair)
+				}
+				action.Env = append(action.Env, pair)
+			}
+		case "show":
+			show, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("action %s: invalid show value %q", name, value)
+			}
+			action.Show = show
+		default:
+			return nil, fmt.Errorf("action %s: unknown option %s", name, option)
+		}
+	}
+
+	return actions, nil
+}
+
+// SplitCommandLine splits a command line into arguments, honouring single
+// quotes, double quotes and backslash escapes. No shell expansion is done.
+func SplitCommandLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// GetCommandAction returns the named [actions] entry
+func (c *Config) GetCommandAction(name string) (*CommandAction, bool) {
+	action, exists := c.Commands[name]
+	return action, exists
+}
+
+// GetCommandActionNames returns the names of all defined [actions] entries
+func (c *Config) GetCommandActionNames() []string {
+	names := make([]string, 0, len(c.Commands))
+	for name := range c.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+		err  string
+	}{
+		{line: "", want: nil},
+		{line: "   ", want: nil},
+		{line: "/bin/true", want: []string{"/bin/true"}},
+		{line: "  mount  -o ro\t/dev/sda1 ", want: []string{"mount", "-o", "ro", "/dev/sda1"}},
+		{line: `echo "two words"`, want: []string{"echo", "two words"}},
+		{line: `echo 'single "quoted"'`, want: []string{"echo", `single "quoted"`}},
+		{line: `echo "double 'quoted'"`, want: []string{"echo", "double 'quoted'"}},
+		{line: `echo a"b c"d`, want: []string{"echo", "ab cd"}},
+		{line: `echo "" ''`, want: []string{"echo", "", ""}},
+		{line: `echo two\ words`, want: []string{"echo", "two words"}},
+		{line: `echo "say \"hi\""`, want: []string{"echo", `say "hi"`}},
+		{line: `echo 'no \escape'`, want: []string{"echo", `no \escape`}},
+		{line: `echo \\`, want: []string{"echo", `\`}},
+		{line: `echo $HOME ~ *`, want: []string{"echo", "$HOME", "~", "*"}},
+		{line: `echo "open`, err: "unterminated quote"},
+		{line: `echo 'open`, err: "unterminated quote"},
+		{line: `echo trailing\`, err: "trailing backslash"},
+	}
+
+	for _, tt := range tests {
+		got, err := SplitCommandLine(tt.line)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("SplitCommandLine(%q) error = %v, want %q", tt.line, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SplitCommandLine(%q) error = %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitCommandLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCommandActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		section string
+		want    map[string]*CommandAction
+		err     string
+	}{
+		{
+			name:    "empty",
+			section: "",
+			want:    map[string]*CommandAction{},
+		},
+		{
+			name:    "defaults",
+			section: "backup = /usr/local/bin/backup.sh --fast",
+			want: map[string]*CommandAction{
+				"backup": {Name: "backup", Args: []string{"/usr/local/bin/backup.sh", "--fast"}, Timeout: defaultCommandTimeout},
+			},
+		},
+		{
+			name: "all options",
+			section: `backup.user = backup
+backup = "/opt/my tools/backup" --target /mnt/backup
+backup.timeout = 1.5
+backup.env = LABEL=nas, VERBOSE=1,
+backup.show = true`,
+			want: map[string]*CommandAction{
+				"backup": {
+					Name:    "backup",
+					Args:    []string{"/opt/my tools/backup", "--target", "/mnt/backup"},
+					User:    "backup",
+					Timeout: 1500 * time.Millisecond,
+					Env:     []string{"LABEL=nas", "VERBOSE=1"},
+					Show:    true,
+				},
+			},
+		},
+		{
+			name:    "empty command",
+			section: `backup = ""`,
+			err:     "empty command",
+		},
+		{
+			name:    "bad quoting",
+			section: `backup = echo "open`,
+			err:     "unterminated quote",
+		},
+		{
+			name:    "option for undefined action",
+			section: "backup.user = backup",
+			err:     "undefined action backup",
+		},
+		{
+			name:    "zero timeout",
+			section: "backup = true\nbackup.timeout = 0",
+			err:     "invalid timeout",
+		},
+		{
+			name:    "non-numeric timeout",
+			section: "backup = true\nbackup.timeout = soon",
+			err:     "invalid timeout",
+		},
+		{
+			name:    "env without value",
+			section: "backup = true\nbackup.env = VERBOSE",
+			err:     "invalid env entry",
+		},
+		{
+			name:    "bad show",
+			section: "backup = true\nbackup.show = maybe",
+			err:     "invalid show value",
+		},
+		{
+			name:    "unknown option",
+			section: "backup = true\nbackup.group = wheel",
+			err:     "unknown option group",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ini.Load([]byte("[actions]\n" + tt.section + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadCommandActions(cfg.Section("actions"))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("actions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hostfs"
+)
+
+// Built-in board profiles
+//
+//go:embed boards/*.conf
+var embeddedBoards embed.FS
+
+// BoardsDir holds board profiles that override or extend the built-in ones.
+// A section with the name of a built-in profile only replaces the keys it sets.
+const BoardsDir = "/etc/rockpi-penta/boards.d"
+
+// Board used when nothing matched
+const fallbackBoard = "raspberry-pi-5"
+
+// BoardProfile describes how the Penta HAT is wired on a board
+type BoardProfile struct {
+	ID         string   `ini:"-"`
+	Name       string   `ini:"name"`
+	Compatible []string `ini:"-"` // All must appear in the device-tree compatible
+	Model      []string `ini:"-"` // Any may appear in the board model
+	Confidence int      `ini:"confidence"`
+
+	ButtonChip  string `ini:"button-chip"`
+	ButtonLine  string `ini:"button-line"`
+	FanChip     string `ini:"fan-chip"`
+	FanLine     string `ini:"fan-line"`
+	FanPolarity string `ini:"fan-polarity"`
+	HardwarePWM bool   `ini:"hardware-pwm"`
+	PWMChip     string `ini:"pwm-chip"`
+	PWMChannel  int    `ini:"pwm-channel"`
+	GPIOChip    string `ini:"gpio-chip"`
+	I2CBus      string `ini:"i2c-bus"`
+	OLEDReset   string `ini:"oled-reset"`
+	Notes       string `ini:"notes"`
+
+	// Stable identifiers, resolved to the chip numbers above at runtime
+	PWMController string `ini:"pwm-controller"` // Address of the PWM controller, e.g. febf0020.pwm
+	GPIOLabel     string `ini:"gpio-label"`     // Label of the GPIO chip, e.g. pinctrl-rp1
+}
+
+// LoadBoardProfiles reads the built-in profiles and the overrides in BoardsDir
+func LoadBoardProfiles() (map[string]*BoardProfile, error) {
+	var sources []interface{}
+
+	entries, err := embeddedBoards.ReadDir("boards")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data, err := embeddedBoards.ReadFile("boards/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, data)
+	}
+
+	// Files are applied in name order, later files win
+	overrides, _ := hostfs.Glob(filepath.Join(BoardsDir, "*.conf"))
+	sort.Strings(overrides)
+	for _, path := range overrides {
+		if _, err := ini.Load(hostfs.Path(path)); err != nil {
+			log.Printf("Warning: ignoring board profile file %s: %v", path, err)
+			continue
+		}
+		sources = append(sources, hostfs.Path(path))
+	}
+
+	cfg, err := ini.Load(sources[0], sources[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load board profiles: %v", err)
+	}
+
+	profiles := make(map[string]*BoardProfile)
+	for _, section := range cfg.Sections() {
+		if section.Name() == ini.DefaultSection {
+			continue
+		}
+
+		profile := &BoardProfile{
+			ID:          section.Name(),
+			FanPolarity: string(FanPolarityInverted),
+			OLEDReset:   "D23",
+		}
+		if err := section.MapTo(profile); err != nil {
+			log.Printf("Warning: invalid board profile %s: %v", section.Name(), err)
+			continue
+		}
+		profile.Compatible = strings.Fields(strings.ToLower(section.Key("compatible").String()))
+		for _, model := range strings.Split(strings.ToLower(section.Key("model").String()), ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				profile.Model = append(profile.Model, model)
+			}
+		}
+		profiles[profile.ID] = profile
+	}
+
+	return profiles, nil
+}
+
+// matchesCompatible reports whether every compatible string of the profile
+// appears in the device-tree compatible list
+func (p *BoardProfile) matchesCompatible(compatible string) bool {
+	if len(p.Compatible) == 0 || compatible == "" {
+		return false
+	}
+	compatible = strings.ToLower(compatible)
+	for _, want := range p.Compatible {
+		if !strings.Contains(compatible, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesModel reports whether the board model contains one of the models
+// of the profile
+func (p *BoardProfile) matchesModel(model string) bool {
+	model = strings.ToLower(model)
+	for _, want := range p.Model {
+		if model != "" && strings.Contains(model, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchBoardProfile returns the most confident profile matching the model or
+// compatible string. Ties go to the profile with the more specific rule.
+func matchBoardProfile(profiles map[string]*BoardProfile, model, compatible string) *BoardProfile {
+	var best *BoardProfile
+	for _, id := range sortedProfileIDs(profiles) {
+		profile := profiles[id]
+		if !profile.matchesCompatible(compatible) && !profile.matchesModel(model) {
+			continue
+		}
+		if best == nil || profile.Confidence > best.Confidence ||
+			(profile.Confidence == best.Confidence && len(profile.Compatible) > len(best.Compatible)) {
+			best = profile
+		}
+	}
+	return best
+}
+
+// sortedProfileIDs returns the profile IDs in a stable order
+func sortedProfileIDs(profiles map[string]*BoardProfile) []string {
+	ids := make([]string, 0, len(profiles))
+	for id := range profiles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -4,20 +4,31 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"gopkg.in/ini.v1"
 )
 
 // Config holds all configuration values
 type Config struct {
-	Fan    FanConfig    `ini:"fan"`
-	Key    KeyConfig    `ini:"key"`
-	Time   TimeConfig   `ini:"time"`
-	Slider SliderConfig `ini:"slider"`
-	OLED   OLEDConfig   `ini:"oled"`
+	Fan      FanConfig      `ini:"fan"`
+	Key      KeyConfig      `ini:"key"`
+	Time     TimeConfig     `ini:"time"`
+	Slider   SliderConfig   `ini:"slider"`
+	OLED     OLEDConfig     `ini:"oled"`
+	Disk     DiskConfig     `ini:"disk"`
+	Critical CriticalConfig `ini:"critical"`
+
+	// Scheduled fan profiles from [profile.<name>]
+	Profiles []FanProfile `ini:"-"`
+
+	// Named commands from [actions]
+	Commands map[string]*CommandAction `ini:"-"`
 
 	// Runtime state
 	RunState    *int32
@@ -31,17 +42,73 @@ type FanConfig struct {
 	Lv1 float64 `ini:"lv1"`
 	Lv2 float64 `ini:"lv2"`
 	Lv3 float64 `ini:"lv3"`
+
+	// Disk aware policy
+	DiskAware bool    `ini:"disk-aware"`
+	DiskLv0   float64 `ini:"disk-lv0"`
+	DiskLv1   float64 `ini:"disk-lv1"`
+	DiskLv2   float64 `ini:"disk-lv2"`
+	DiskLv3   float64 `ini:"disk-lv3"`
+	QuietTemp float64 `ini:"quiet-temp"`
+	QuietMax  float64 `ini:"quiet-max"`
+
+	// Above this temperature schedule profile caps are ignored
+	OverrideTemp float64 `ini:"override-temp"`
+
+	// Fail-safe: fan power in percent used on sensor failures, panics and
+	// exit, and how many consecutive failed readings trigger it
+	SafeDuty       float64 `ini:"safe-duty"`
+	SensorFailures int     `ini:"sensor-failures"`
+
+	// PWM output, unset values come from the environment and the detected board
+	Type         string  `ini:"type"`          // 4-wire or 2-wire
+	PWMChip      string  `ini:"pwm-chip"`      // Hardware PWM chip number
+	PWMChannel   int     `ini:"pwm-channel"`   // Hardware PWM channel, -1 = unset
+	PWMFrequency float64 `ini:"pwm-frequency"` // Hz, 0 = chosen by fan type
+	SoftPWM      string  `ini:"soft-pwm"`      // Software PWM backend: precise or ticker
+
+	// Soft start: full power for kick milliseconds when starting from
+	// standstill, and the maximum change of fan power in percent per second
+	Kick     int     `ini:"kick"`
+	RampRate float64 `ini:"ramp-rate"`
+
+	// Temperature sampling: seconds between samples and how they are
+	// smoothed (none, ema or median over smoothing-window samples)
+	Interval        float64 `ini:"interval"`
+	Smoothing       string  `ini:"smoothing"`
+	SmoothingWindow int     `ini:"smoothing-window"`
+
+	// Manual override: fan power in percent and seconds until automatic
+	// control resumes
+	ManualDuty float64 `ini:"manual-duty"`
+	ManualTime int     `ini:"manual-time"`
+}
+
+// FanProfile is a time-of-day fan curve from a [profile.<name>] section.
+// Outside every scheduled profile the [fan] thresholds apply uncapped.
+type FanProfile struct {
+	Name  string  `ini:"-"`
+	Start string  `ini:"start"`
+	End   string  `ini:"end"`
+	Max   float64 `ini:"max"` // Maximum fan power in percent
+	Lv0   float64 `ini:"lv0"`
+	Lv1   float64 `ini:"lv1"`
+	Lv2   float64 `ini:"lv2"`
+	Lv3   float64 `ini:"lv3"`
 }
 
+// KeyConfig maps button gesture names (click, twice, triple, clicks-N,
+// press, press-<seconds>, hold) to actions
 type KeyConfig struct {
-	Click string `ini:"click"`
-	Twice string `ini:"twice"`
-	Press string `ini:"press"`
+	Actions map[string]string `ini:"-"`
+	Confirm bool              `ini:"confirm"`
 }
 
 type TimeConfig struct {
-	Twice float64 `ini:"twice"`
-	Press float64 `ini:"press"`
+	Twice   float64 `ini:"twice"`
+	Press   float64 `ini:"press"`
+	Repeat  float64 `ini:"repeat"`
+	Confirm float64 `ini:"confirm"`
 }
 
 type SliderConfig struct {
@@ -50,8 +117,57 @@ type SliderConfig struct {
 }
 
 type OLEDConfig struct {
-	Rotate bool `ini:"rotate"`
-	FTemp  bool `ini:"f-temp"`
+	Rotate     bool    `ini:"rotate"`
+	FTemp      bool    `ini:"f-temp"`
+	Contrast   int     `ini:"contrast"`
+	Shift      bool    `ini:"shift"`
+	ShiftTime  float64 `ini:"shift-time"`
+	Idle       float64 `ini:"idle"`
+	NightStart string  `ini:"night-start"`
+	NightEnd   string  `ini:"night-end"`
+}
+
+// DiskConfig controls spinning down idle SATA disks
+type DiskConfig struct {
+	Standby  float64            `ini:"standby"`  // Idle seconds before standby, 0 disables
+	Interval float64            `ini:"interval"` // Seconds between activity checks
+	PerDisk  map[string]float64 `ini:"-"`        // standby.<device> overrides
+}
+
+// StandbyTimeout returns how long a disk may stay idle before it is spun down
+func (d DiskConfig) StandbyTimeout(device string) time.Duration {
+	seconds := d.Standby
+	if override, exists := d.PerDisk[device]; exists {
+		seconds = override
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// CriticalConfig controls the overheat shutdown
+type CriticalConfig struct {
+	Temp     float64 `ini:"temp"`      // CPU temperature that triggers a shutdown, 0 disables
+	DiskTemp float64 `ini:"disk-temp"` // Disk temperature that triggers a shutdown, 0 disables
+	Grace    float64 `ini:"grace"`     // Seconds to wait for the temperature to recover
+	Hook     string  `ini:"hook"`      // Command run once when the threshold is exceeded
+}
+
+// FanPolarity describes which PWM pin level drives the fan
+type FanPolarity string
+
+const (
+	FanPolarityNormal   FanPolarity = "normal"   // Pin high drives the fan
+	FanPolarityInverted FanPolarity = "inverted" // Pin low drives the fan
+)
+
+// ParseFanPolarity parses a FAN_POLARITY value
+func ParseFanPolarity(value string) (FanPolarity, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "normal":
+		return FanPolarityNormal, nil
+	case "inverted", "inversed":
+		return FanPolarityInverted, nil
+	}
+	return "", fmt.Errorf("invalid fan polarity %q (expected normal or inverted)", value)
 }
 
 // Hardware environment configuration
@@ -63,7 +179,14 @@ type HardwareConfig struct {
 	ButtonLine  string
 	FanChip     string
 	FanLine     string
+	FanPolarity FanPolarity
 	HardwarePWM bool
+
+	// Optional PWM settings, see GetPWMSettings
+	PWMChip      string
+	PWMChannel   string
+	PWMFrequency string
+	FanType      string
 }
 
 var (
@@ -98,27 +221,68 @@ func Load() *Config {
 
 func setDefaults(c *Config) {
 	c.Fan = FanConfig{
-		Lv0: 35,
-		Lv1: 40,
-		Lv2: 45,
-		Lv3: 50,
+		Lv0:       35,
+		Lv1:       40,
+		Lv2:       45,
+		Lv3:       50,
+		DiskAware: false,
+		DiskLv0:   40,
+		DiskLv1:   45,
+		DiskLv2:   50,
+		DiskLv3:   55,
+		QuietTemp: 40,
+		QuietMax:  25,
+
+		OverrideTemp: 60,
+
+		SafeDuty:       100,
+		SensorFailures: 5,
+
+		PWMChannel: -1,
+		SoftPWM:    "ticker",
+
+		Interval:        1,
+		Smoothing:       "ema",
+		SmoothingWindow: 5,
+
+		ManualDuty: 100,
+		ManualTime: 1800,
 	}
 	c.Key = KeyConfig{
-		Click: "slider",
-		Twice: "switch",
-		Press: "none",
+		Actions: map[string]string{
+			"click": "slider",
+			"twice": "switch",
+			"press": "menu",
+		},
+		Confirm: true,
 	}
 	c.Time = TimeConfig{
-		Twice: 0.7,
-		Press: 1.8,
+		Twice:   0.7,
+		Press:   1.8,
+		Repeat:  0.5,
+		Confirm: 5,
 	}
 	c.Slider = SliderConfig{
 		Auto: true,
 		Time: 10,
 	}
+	c.Disk = DiskConfig{
+		Standby:  0,
+		Interval: 30,
+		PerDisk:  make(map[string]float64),
+	}
+	c.Critical = CriticalConfig{
+		Temp:     0,
+		DiskTemp: 0,
+		Grace:    60,
+	}
 	c.OLED = OLEDConfig{
-		Rotate: false,
-		FTemp:  false,
+		Rotate:    false,
+		FTemp:     false,
+		Contrast:  255,
+		Shift:     true,
+		ShiftTime: 60,
+		Idle:      0,
 	}
 }
 
@@ -128,7 +292,69 @@ func loadFromFile(c *Config) error {
 		return err
 	}
 
-	return cfg.MapTo(c)
+	if err := cfg.MapTo(c); err != nil {
+		return err
+	}
+
+	// Every other key in [key] binds a gesture to an action
+	for _, key := range cfg.Section("key").Keys() {
+		if key.Name() == "confirm" {
+			continue
+		}
+		c.Key.Actions[key.Name()] = strings.TrimSpace(key.String())
+	}
+
+	// Per-disk standby overrides: standby.<device> = seconds
+	for _, key := range cfg.Section("disk").Keys() {
+		if device, found := strings.CutPrefix(key.Name(), "standby."); found {
+			seconds, err := key.Float64()
+			if err != nil {
+				log.Printf("Warning: invalid %s value %q", key.Name(), key.String())
+				continue
+			}
+			c.Disk.PerDisk[device] = seconds
+		}
+	}
+
+	// Scheduled fan profiles inherit unset thresholds from [fan]
+	c.Profiles = nil
+	for _, section := range cfg.Sections() {
+		name, found := strings.CutPrefix(section.Name(), "profile.")
+		if !found {
+			continue
+		}
+		profile := FanProfile{
+			Name: name,
+			Max:  100,
+			Lv0:  c.Fan.Lv0,
+			Lv1:  c.Fan.Lv1,
+			Lv2:  c.Fan.Lv2,
+			Lv3:  c.Fan.Lv3,
+		}
+		if err := section.MapTo(&profile); err != nil {
+			log.Printf("Warning: ignoring invalid [%s] section: %v", section.Name(), err)
+			continue
+		}
+		if _, err := parseClock(profile.Start); err != nil {
+			log.Printf("Warning: ignoring [%s] section: start: %v", section.Name(), err)
+			continue
+		}
+		if _, err := parseClock(profile.End); err != nil {
+			log.Printf("Warning: ignoring [%s] section: end: %v", section.Name(), err)
+			continue
+		}
+		c.Profiles = append(c.Profiles, profile)
+	}
+
+	// A broken [actions] section shouldn't discard the rest of the file
+	commands, err := loadCommandActions(cfg.Section("actions"))
+	if err != nil {
+		log.Printf("Warning: ignoring invalid [actions] section: %v", err)
+	} else {
+		c.Commands = commands
+	}
+
+	return nil
 }
 
 func loadHardwareConfig() *HardwareConfig {
@@ -168,6 +394,7 @@ func loadHardwareConfig() *HardwareConfig {
 			"BUTTON_LINE":  "17",
 			"FAN_CHIP":     "4",
 			"FAN_LINE":     "27",
+			"FAN_POLARITY": string(FanPolarityInverted),
 			"HARDWARE_PWM": "0",
 			"I2C_BUS":      "/dev/i2c-1",
 		}
@@ -182,7 +409,19 @@ func loadHardwareConfig() *HardwareConfig {
 		FanChip:     getEnvDefaultWithFallback("FAN_CHIP", defaults["FAN_CHIP"]),
 		FanLine:     getEnvDefaultWithFallback("FAN_LINE", defaults["FAN_LINE"]),
 		HardwarePWM: getEnvDefaultBoolWithFallback("HARDWARE_PWM", defaults["HARDWARE_PWM"] == "1"),
+
+		PWMChip:      getEnvDefaultWithFallback("PWMCHIP", defaults["PWMCHIP"]),
+		PWMChannel:   getEnvDefaultWithFallback("PWM_CHANNEL", defaults["PWM_CHANNEL"]),
+		PWMFrequency: os.Getenv("PWM_FREQUENCY"),
+		FanType:      os.Getenv("FAN_TYPE"),
+	}
+
+	polarity, err := ParseFanPolarity(getEnvDefaultWithFallback("FAN_POLARITY", defaults["FAN_POLARITY"]))
+	if err != nil {
+		log.Printf("Warning: %v, using %s", err, FanPolarityInverted)
+		polarity = FanPolarityInverted
 	}
+	hw.FanPolarity = polarity
 
 	// Set I2C_BUS environment variable if not set and we have a detected value
 	if os.Getenv("I2C_BUS") == "" && defaults["I2C_BUS"] != "" {
@@ -307,40 +546,116 @@ func (c *Config) GetDiskDevices() []string {
 	return devices
 }
 
-// GetFanDutyCycle calculates the fan duty cycle based on temperature
+// GetFanProfile returns the fan profile scheduled at the given time, or the
+// uncapped "day" profile built from [fan] when none is scheduled
+func (c *Config) GetFanProfile(now time.Time) FanProfile {
+	for _, profile := range c.Profiles {
+		if InTimeWindow(profile.Start, profile.End, now) {
+			return profile
+		}
+	}
+	return FanProfile{
+		Name: "day",
+		Max:  100,
+		Lv0:  c.Fan.Lv0,
+		Lv1:  c.Fan.Lv1,
+		Lv2:  c.Fan.Lv2,
+		Lv3:  c.Fan.Lv3,
+	}
+}
+
+// GetFanDutyCycle returns the fan power in percent for the temperature using
+// the thresholds of the profile scheduled now. The profile cap is applied
+// separately by the fan controller.
 func (c *Config) GetFanDutyCycle(temp float64) float64 {
 	if !c.IsRunning() {
-		return 0.999 // Off state
+		return 0 // Off state
+	}
+	profile := c.GetFanProfile(time.Now())
+	return thresholdDutyCycle(temp, profile.Lv0, profile.Lv1, profile.Lv2, profile.Lv3)
+}
+
+// GetDiskFanDutyCycle returns the fan power in percent for the disk temperature
+func (c *Config) GetDiskFanDutyCycle(temp float64) float64 {
+	if !c.IsRunning() {
+		return 0 // Off state
+	}
+	return thresholdDutyCycle(temp, c.Fan.DiskLv0, c.Fan.DiskLv1, c.Fan.DiskLv2, c.Fan.DiskLv3)
+}
+
+// thresholdDutyCycle maps a temperature to fan power in percent using four thresholds
+func thresholdDutyCycle(temp, lv0, lv1, lv2, lv3 float64) float64 {
+	// Temperature thresholds to fan power mapping (from Python lv2dc)
+	if temp >= lv3 {
+		return 100
 	}
+	if temp >= lv2 {
+		return 75
+	}
+	if temp >= lv1 {
+		return 50
+	}
+	if temp >= lv0 {
+		return 25
+	}
+	return 0 // Off
+}
+
+// InNightSchedule reports whether the display should be off at the given time
+func (o OLEDConfig) InNightSchedule(now time.Time) bool {
+	return InTimeWindow(o.NightStart, o.NightEnd, now)
+}
 
-	// Temperature thresholds to duty cycle mapping (from Python lv2dc)
-	if temp >= c.Fan.Lv3 {
-		return 0.0 // 100% power
+// InTimeWindow reports whether now falls between start and end ("HH:MM").
+// Windows may wrap around midnight; an empty or invalid bound disables the window.
+func InTimeWindow(start, end string, now time.Time) bool {
+	startMin, err := parseClock(start)
+	if err != nil {
+		return false
 	}
-	if temp >= c.Fan.Lv2 {
-		return 0.25 // 75% power
+	endMin, err := parseClock(end)
+	if err != nil {
+		return false
 	}
-	if temp >= c.Fan.Lv1 {
-		return 0.5 // 50% power
+
+	cur := now.Hour()*60 + now.Minute()
+	if startMin <= endMin {
+		return cur >= startMin && cur < endMin
 	}
-	if temp >= c.Fan.Lv0 {
-		return 0.75 // 25% power
+	return cur >= startMin || cur < endMin
+}
+
+// parseClock converts "HH:MM" to minutes since midnight
+func parseClock(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty time")
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: %v", value, err)
 	}
-	return 0.999 // Off
+	return t.Hour()*60 + t.Minute(), nil
 }
 
 // GetKeyAction returns the action for a given key event
 func (c *Config) GetKeyAction(key string) string {
-	switch key {
-	case "click":
-		return c.Key.Click
-	case "twice":
-		return c.Key.Twice
-	case "press":
-		return c.Key.Press
-	default:
-		return "none"
+	if action, exists := c.Key.Actions[key]; exists && action != "" {
+		return action
+	}
+	return "none"
+}
+
+// GetBoundGestures returns the gesture names that are mapped to an action
+func (c *Config) GetBoundGestures() []string {
+	var gestures []string
+	for gesture, action := range c.Key.Actions {
+		if action != "" && action != "none" {
+			gestures = append(gestures, gesture)
+		}
 	}
+	sort.Strings(gestures)
+	return gestures
 }
 
 // String returns a string representation of the configuration
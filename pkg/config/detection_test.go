@@ -0,0 +1,98 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hostfs"
+)
+
+// expectedReport is what a fixture's expected.env says detection produces
+type expectedReport struct {
+	boardType  string
+	confidence int
+	access     map[string]bool
+	env        map[string]string
+}
+
+// readExpected parses an expected.env in the device-info --format env layout
+func readExpected(t *testing.T, path string) expectedReport {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	want := expectedReport{access: map[string]bool{}, env: map[string]string{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# board_type="):
+			for _, field := range strings.Fields(strings.TrimPrefix(line, "# ")) {
+				key, value, _ := strings.Cut(field, "=")
+				switch key {
+				case "board_type":
+					want.boardType = value
+				case "confidence":
+					want.confidence, _ = strconv.Atoi(value)
+				}
+			}
+		case strings.HasPrefix(line, "# access."):
+			key, value, _ := strings.Cut(strings.TrimPrefix(line, "# access."), "=")
+			want.access[key] = value == "true"
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			key, value, _ := strings.Cut(line, "=")
+			want.env[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return want
+}
+
+func TestDetectDeviceFixtures(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*/expected.env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata")
+	}
+
+	for _, expected := range fixtures {
+		dir := filepath.Dir(expected)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			root, err := filepath.Abs(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hostfs.SetRoot(root)
+			t.Cleanup(func() { hostfs.SetRoot("/") })
+
+			want := readExpected(t, expected)
+			device := DetectDevice()
+
+			if device.BoardType != want.boardType {
+				t.Errorf("BoardType = %q, want %q", device.BoardType, want.boardType)
+			}
+			if device.Confidence != want.confidence {
+				t.Errorf("Confidence = %d, want %d", device.Confidence, want.confidence)
+			}
+			if env := device.GetRecommendedEnvVars(); !reflect.DeepEqual(env, want.env) {
+				t.Errorf("env = %v, want %v", env, want.env)
+			}
+			if access := device.VerifyHardwareAccess(); !reflect.DeepEqual(access, want.access) {
+				t.Errorf("access = %v, want %v", access, want.access)
+			}
+		})
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"fmt"
 	"log"
-	"os"
 	"regexp"
+	"strconv"
 	"strings"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hostfs"
 )
 
 // DeviceInfo holds information about the detected device
@@ -17,8 +19,12 @@ type DeviceInfo struct {
 	ButtonLine     string
 	FanChip        string
 	FanLine        string
+	FanPolarity    FanPolarity
 	HardwarePWM    bool
+	PWMChip        string
+	PWMChannel     int
 	I2CBus         string
+	OLEDReset      string
 	GPIOChipPath   string
 	Confidence     int // 0-100, how confident we are in the detection
 	DetectionNotes []string
@@ -31,14 +37,19 @@ func DetectDevice() *DeviceInfo {
 		DetectionNotes: []string{},
 	}
 
+	profiles, err := LoadBoardProfiles()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	// Try multiple detection methods
-	detectFromCPUInfo(device)
-	detectFromDeviceTree(device)
+	detectFromCPUInfo(device, profiles)
+	detectFromDeviceTree(device, profiles)
 	detectFromGPIOChips(device)
 	detectFromI2CBuses(device)
 
 	// Set final configuration based on detection
-	setDeviceConfiguration(device)
+	setDeviceConfiguration(device, profiles)
 
 	log.Printf("Device detected: %s (confidence: %d%%)", device.BoardType, device.Confidence)
 	for _, note := range device.DetectionNotes {
@@ -48,9 +59,10 @@ func DetectDevice() *DeviceInfo {
 	return device
 }
 
-// detectFromCPUInfo reads /proc/cpuinfo to identify the board
-func detectFromCPUInfo(device *DeviceInfo) {
-	file, err := os.Open("/proc/cpuinfo")
+// detectFromCPUInfo matches the model line of /proc/cpuinfo against the
+// board profiles, it is trusted a little less than the device tree
+func detectFromCPUInfo(device *DeviceInfo, profiles map[string]*BoardProfile) {
+	file, err := hostfs.Open("/proc/cpuinfo")
 	if err != nil {
 		device.DetectionNotes = append(device.DetectionNotes, "Could not read /proc/cpuinfo")
 		return
@@ -59,122 +71,41 @@ func detectFromCPUInfo(device *DeviceInfo) {
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := strings.ToLower(scanner.Text())
-
-		// Look for Raspberry Pi indicators
-		if strings.Contains(line, "raspberry pi") {
-			if strings.Contains(line, "model") {
-				device.Model = strings.TrimSpace(scanner.Text())
-				if strings.Contains(line, "pi 5") {
-					device.BoardType = "raspberry-pi-5"
-					device.Confidence = 90
-				} else if strings.Contains(line, "pi 4") {
-					device.BoardType = "raspberry-pi-4"
-					device.Confidence = 90
-				} else if strings.Contains(line, "pi 3") {
-					device.BoardType = "raspberry-pi-3"
-					device.Confidence = 85
-				}
-				device.DetectionNotes = append(device.DetectionNotes, "Detected from /proc/cpuinfo: "+device.Model)
-			}
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.ToLower(strings.TrimSpace(key)) != "model" {
+			continue
 		}
 
-		// Look for Rock Pi indicators
-		if strings.Contains(line, "rockchip") || strings.Contains(line, "rk3399") || strings.Contains(line, "rk3588") {
-			if strings.Contains(line, "rk3588") {
-				device.BoardType = "rock-pi-5"
-				device.Confidence = 85
-			} else if strings.Contains(line, "rk3399") {
-				device.BoardType = "rock-pi-4"
-				device.Confidence = 85
-			}
-			device.DetectionNotes = append(device.DetectionNotes, "Detected Rockchip SoC from /proc/cpuinfo")
+		device.Model = strings.TrimSpace(value)
+		if profile := matchBoardProfile(profiles, device.Model, ""); profile != nil {
+			device.BoardType = profile.ID
+			device.Confidence = profile.Confidence - 5
 		}
+		device.DetectionNotes = append(device.DetectionNotes, "Detected from /proc/cpuinfo: "+device.Model)
 	}
 }
 
-// detectFromDeviceTree reads device tree information
-func detectFromDeviceTree(device *DeviceInfo) {
-	// Try to read device tree model
-	if model, err := os.ReadFile("/proc/device-tree/model"); err == nil {
-		modelStr := strings.TrimSpace(string(model))
-		device.Model = modelStr
-
-		modelLower := strings.ToLower(modelStr)
-
-		// Raspberry Pi detection
-		if strings.Contains(modelLower, "raspberry pi 5") {
-			device.BoardType = "raspberry-pi-5"
-			device.Confidence = 95
-		} else if strings.Contains(modelLower, "raspberry pi 4") {
-			device.BoardType = "raspberry-pi-4"
-			device.Confidence = 95
-		} else if strings.Contains(modelLower, "raspberry pi 3") {
-			device.BoardType = "raspberry-pi-3"
-			device.Confidence = 95
-		}
-
-		// Rock Pi detection - more specific
-		if strings.Contains(modelLower, "rock 5a") || strings.Contains(modelLower, "rock5a") {
-			device.BoardType = "rock-5a"
-			device.Confidence = 95
-		} else if strings.Contains(modelLower, "rock 5") || strings.Contains(modelLower, "rock5") {
-			device.BoardType = "rock-pi-5"
-			device.Confidence = 90
-		} else if strings.Contains(modelLower, "rock 4") || strings.Contains(modelLower, "rock4") {
-			device.BoardType = "rock-pi-4"
-			device.Confidence = 90
-		} else if strings.Contains(modelLower, "rock 3c") || strings.Contains(modelLower, "rock3c") {
-			device.BoardType = "rock-3c"
-			device.Confidence = 90
-		} else if strings.Contains(modelLower, "rock 3") || strings.Contains(modelLower, "rock3") {
-			device.BoardType = "rock-pi-3"
-			device.Confidence = 90
-		}
+// detectFromDeviceTree matches the device tree model and compatible strings
+// against the board profiles
+func detectFromDeviceTree(device *DeviceInfo, profiles map[string]*BoardProfile) {
+	var model, compatible string
 
-		device.DetectionNotes = append(device.DetectionNotes, "Device tree model: "+modelStr)
+	// Try to read device tree model
+	if data, err := hostfs.ReadFile("/proc/device-tree/model"); err == nil {
+		model = strings.TrimRight(strings.TrimSpace(string(data)), "\x00")
+		device.Model = model
+		device.DetectionNotes = append(device.DetectionNotes, "Device tree model: "+model)
 	}
 
 	// Check compatible string
-	if compatible, err := os.ReadFile("/proc/device-tree/compatible"); err == nil {
-		compatStr := string(compatible)
-		compatLower := strings.ToLower(compatStr)
-
-		// Raspberry Pi compatible strings
-		if strings.Contains(compatStr, "raspberrypi,5") {
-			device.BoardType = "raspberry-pi-5"
-			device.Confidence = 95
-		} else if strings.Contains(compatStr, "raspberrypi,4") {
-			device.BoardType = "raspberry-pi-4"
-			device.Confidence = 95
-		} else if strings.Contains(compatStr, "raspberrypi,3") {
-			device.BoardType = "raspberry-pi-3"
-			device.Confidence = 95
-		}
-
-		// Rockchip SoC detection
-		if strings.Contains(compatStr, "rockchip,rk3588") {
-			if strings.Contains(compatLower, "rock-5a") {
-				device.BoardType = "rock-5a"
-				device.Confidence = 95
-			} else {
-				device.BoardType = "rock-pi-5"
-				device.Confidence = 90
-			}
-		} else if strings.Contains(compatStr, "rockchip,rk3399") {
-			device.BoardType = "rock-pi-4"
-			device.Confidence = 90
-		} else if strings.Contains(compatStr, "rockchip,rk3566") {
-			if strings.Contains(compatLower, "rock-3c") {
-				device.BoardType = "rock-3c"
-				device.Confidence = 90
-			} else {
-				device.BoardType = "rock-pi-3"
-				device.Confidence = 85
-			}
-		}
+	if data, err := hostfs.ReadFile("/proc/device-tree/compatible"); err == nil {
+		compatible = string(data)
+		device.DetectionNotes = append(device.DetectionNotes, "Device tree compatible: "+strings.Trim(strings.ReplaceAll(compatible, "\x00", ", "), ", "))
+	}
 
-		device.DetectionNotes = append(device.DetectionNotes, "Device tree compatible: "+strings.ReplaceAll(compatStr, "\x00", ", "))
+	if profile := matchBoardProfile(profiles, model, compatible); profile != nil {
+		device.BoardType = profile.ID
+		device.Confidence = profile.Confidence
 	}
 }
 
@@ -183,7 +114,7 @@ func detectFromGPIOChips(device *DeviceInfo) {
 	chipDirs := []string{"/sys/class/gpio", "/dev"}
 
 	for _, dir := range chipDirs {
-		entries, err := os.ReadDir(dir)
+		entries, err := hostfs.ReadDir(dir)
 		if err != nil {
 			continue
 		}
@@ -220,7 +151,7 @@ func detectFromGPIOChips(device *DeviceInfo) {
 func detectFromI2CBuses(device *DeviceInfo) {
 	i2cDevs := []string{}
 
-	entries, err := os.ReadDir("/dev")
+	entries, err := hostfs.ReadDir("/dev")
 	if err == nil {
 		for _, entry := range entries {
 			name := entry.Name()
@@ -244,114 +175,59 @@ func detectFromI2CBuses(device *DeviceInfo) {
 	}
 }
 
-// setDeviceConfiguration sets the final GPIO configuration based on detected board type
-func setDeviceConfiguration(device *DeviceInfo) {
-	switch device.BoardType {
-	// Raspberry Pi configurations
-	case "raspberry-pi-5":
-		device.ButtonChip = "4"
-		device.ButtonLine = "17"
-		device.FanChip = "4"
-		device.FanLine = "27"
-		device.HardwarePWM = false
-		device.GPIOChipPath = "/dev/gpiochip4"
-		if device.I2CBus == "" {
-			device.I2CBus = "/dev/i2c-1"
+// setDeviceConfiguration applies the board profile of the detected board type
+func setDeviceConfiguration(device *DeviceInfo, profiles map[string]*BoardProfile) {
+	profile := profiles[device.BoardType]
+	if profile == nil {
+		// Fallback to Raspberry Pi 5 defaults (most common current setup)
+		device.BoardType = "unknown-fallback-rpi5"
+		device.Confidence = 30
+		device.DetectionNotes = append(device.DetectionNotes, "Unknown board, using Raspberry Pi 5 defaults")
+		profile = profiles[fallbackBoard]
+		if profile == nil {
+			return
 		}
+	}
 
-	case "raspberry-pi-4":
-		device.ButtonChip = "0" // RPI4 uses gpiochip0 according to Python implementation
-		device.ButtonLine = "17"
-		device.FanChip = "0"
-		device.FanLine = "27"
-		device.HardwarePWM = false
-		device.GPIOChipPath = "/dev/gpiochip0"
-		if device.I2CBus == "" {
-			device.I2CBus = "/dev/i2c-1"
-		}
+	device.ButtonChip = profile.ButtonChip
+	device.ButtonLine = profile.ButtonLine
+	device.FanChip = profile.FanChip
+	device.FanLine = profile.FanLine
+	device.HardwarePWM = profile.HardwarePWM
+	device.PWMChip = profile.PWMChip
+	device.PWMChannel = profile.PWMChannel
+	device.GPIOChipPath = profile.GPIOChip
+	device.OLEDReset = profile.OLEDReset
+	resolveHardware(device, profile)
+
+	polarity, err := ParseFanPolarity(profile.FanPolarity)
+	if err != nil {
+		device.DetectionNotes = append(device.DetectionNotes, fmt.Sprintf("Board profile %s: %v", profile.ID, err))
+		polarity = FanPolarityInverted
+	}
+	device.FanPolarity = polarity
 
-	case "raspberry-pi-3", "raspberry-pi-generic":
-		device.ButtonChip = "0"
-		device.ButtonLine = "17"
-		device.FanChip = "0"
-		device.FanLine = "27"
-		device.HardwarePWM = false
-		device.GPIOChipPath = "/dev/gpiochip0"
-		if device.I2CBus == "" {
-			device.I2CBus = "/dev/i2c-1"
+	// The bus of the profile wins over the scanned one when it exists
+	if profile.I2CBus != "" {
+		if _, err := hostfs.Stat(profile.I2CBus); err == nil || device.I2CBus == "" {
+			device.I2CBus = profile.I2CBus
 		}
+	}
 
-	// Rock Pi configurations
-	case "rock-pi-5", "rock-5a":
-		device.ButtonChip = "4"
-		device.ButtonLine = "11"
-		device.HardwarePWM = true
-		device.GPIOChipPath = "/dev/gpiochip4"
-		if device.I2CBus == "" {
-			device.I2CBus = "/dev/i2c-8" // I2C8 for Rock 5A
-		}
-		// Rock 5A uses PWM chip 14 (or 1 for Armbian)
-		device.DetectionNotes = append(device.DetectionNotes, "Rock 5A: PWM chip may vary (14 or 1) - check /sys/class/pwm/")
-
-	case "rock-pi-4":
-		device.ButtonChip = "4"
-		device.ButtonLine = "18"
-		device.HardwarePWM = true
-		device.GPIOChipPath = "/dev/gpiochip4"
-		if device.I2CBus == "" {
-			device.I2CBus = "/dev/i2c-7" // I2C7 for Rock Pi 4
-		}
-		// Rock Pi 4 uses PWM chip 1 (or 0 for Armbian)
-		device.DetectionNotes = append(device.DetectionNotes, "Rock Pi 4: PWM chip may vary (1 or 0) - check /sys/class/pwm/")
-
-	case "rock-pi-3":
-		device.ButtonChip = "3"
-		device.ButtonLine = "20"
-		device.HardwarePWM = true
-		device.GPIOChipPath = "/dev/gpiochip3"
-		if device.I2CBus == "" {
-			device.I2CBus = "/dev/i2c-3" // I2C3 for Rock Pi 3
-		}
-		device.DetectionNotes = append(device.DetectionNotes, "Rock Pi 3: PWM chip 15")
-
-	case "rock-3c":
-		device.ButtonChip = "3"
-		device.ButtonLine = "1"
-		device.FanChip = "3"
-		device.FanLine = "2"
-		device.HardwarePWM = false // Rock 3C uses software PWM
-		device.GPIOChipPath = "/dev/gpiochip3"
-		if device.I2CBus == "" {
-			device.I2CBus = "/dev/i2c-1" // Uses GPIO pins for I2C
-		}
-		device.DetectionNotes = append(device.DetectionNotes, "Rock 3C: Uses software PWM and GPIO I2C")
-
-	case "rock-pi-generic":
-		// Generic Rock Pi fallback
-		device.ButtonChip = "4"
-		device.ButtonLine = "18"
-		device.HardwarePWM = true
-		device.GPIOChipPath = "/dev/gpiochip4"
-		if device.I2CBus == "" {
-			device.I2CBus = "/dev/i2c-7"
-		}
-		device.DetectionNotes = append(device.DetectionNotes, "Generic Rock Pi configuration - may need manual adjustment")
+	if profile.Notes != "" {
+		device.DetectionNotes = append(device.DetectionNotes, profile.Notes)
+	}
+}
 
-	default:
-		// Fallback to Raspberry Pi 5 defaults (most common current setup)
-		device.BoardType = "unknown-fallback-rpi5"
-		device.ButtonChip = "4"
-		device.ButtonLine = "17"
-		device.FanChip = "4"
-		device.FanLine = "27"
-		device.HardwarePWM = false
-		device.GPIOChipPath = "/dev/gpiochip4"
-		if device.I2CBus == "" {
-			device.I2CBus = "/dev/i2c-1"
-		}
-		device.Confidence = 30
-		device.DetectionNotes = append(device.DetectionNotes, "Unknown board, using Raspberry Pi 5 defaults")
+// DeviceForProfile returns the configuration a board profile resolves to on
+// this system, used to try the wiring of other boards
+func DeviceForProfile(profiles map[string]*BoardProfile, id string) *DeviceInfo {
+	device := &DeviceInfo{BoardType: id, DetectionNotes: []string{}}
+	if profile := profiles[id]; profile != nil {
+		device.Confidence = profile.Confidence
 	}
+	setDeviceConfiguration(device, profiles)
+	return device
 }
 
 // VerifyHardwareAccess tests if the detected hardware configuration is accessible
@@ -360,7 +236,7 @@ func (d *DeviceInfo) VerifyHardwareAccess() map[string]bool {
 
 	// Test I2C bus access
 	if d.I2CBus != "" {
-		if _, err := os.Stat(d.I2CBus); err == nil {
+		if _, err := hostfs.Stat(d.I2CBus); err == nil {
 			results["i2c_bus"] = true
 		} else {
 			results["i2c_bus"] = false
@@ -369,7 +245,7 @@ func (d *DeviceInfo) VerifyHardwareAccess() map[string]bool {
 
 	// Test GPIO chip access
 	if d.GPIOChipPath != "" {
-		if _, err := os.Stat(d.GPIOChipPath); err == nil {
+		if _, err := hostfs.Stat(d.GPIOChipPath); err == nil {
 			results["gpio_chip"] = true
 		} else {
 			results["gpio_chip"] = false
@@ -378,8 +254,8 @@ func (d *DeviceInfo) VerifyHardwareAccess() map[string]bool {
 
 	// Test PWM access (if hardware PWM is expected)
 	if d.HardwarePWM {
-		pwmPath := fmt.Sprintf("/sys/class/pwm/pwmchip%s", d.FanChip)
-		if _, err := os.Stat(pwmPath); err == nil {
+		pwmPath := fmt.Sprintf("/sys/class/pwm/pwmchip%s", d.PWMChip)
+		if _, err := hostfs.Stat(pwmPath); err == nil {
 			results["hardware_pwm"] = true
 		} else {
 			results["hardware_pwm"] = false
@@ -396,22 +272,23 @@ func (d *DeviceInfo) GetRecommendedEnvVars() map[string]string {
 	vars := map[string]string{
 		"BUTTON_CHIP":  d.ButtonChip,
 		"BUTTON_LINE":  d.ButtonLine,
+		"FAN_POLARITY": string(d.FanPolarity),
 		"HARDWARE_PWM": boolToString(d.HardwarePWM),
 		"I2C_BUS":      d.I2CBus,
 		"SDA":          "SDA",
 		"SCL":          "SCL",
-		"OLED_RESET":   "D23",
+		"OLED_RESET":   d.OLEDReset,
 	}
 
 	// Add FAN configuration based on board type
 	if d.HardwarePWM {
-		// For Rock Pi boards using hardware PWM, use PWMCHIP
-		pwmChip := d.getPWMChip()
-		if pwmChip != "" {
-			vars["PWMCHIP"] = pwmChip
+		// For boards using hardware PWM, use PWMCHIP
+		if d.PWMChip != "" {
+			vars["PWMCHIP"] = d.PWMChip
+			vars["PWM_CHANNEL"] = strconv.Itoa(d.PWMChannel)
 		}
 	} else {
-		// For Raspberry Pi boards using software PWM, use FAN_CHIP/FAN_LINE
+		// For boards using software PWM, use FAN_CHIP/FAN_LINE
 		vars["FAN_CHIP"] = d.FanChip
 		vars["FAN_LINE"] = d.FanLine
 	}
@@ -419,22 +296,6 @@ func (d *DeviceInfo) GetRecommendedEnvVars() map[string]string {
 	return vars
 }
 
-// getPWMChip returns the appropriate PWM chip for Rock Pi boards
-func (d *DeviceInfo) getPWMChip() string {
-	switch d.BoardType {
-	case "rock-5a":
-		return "14" // Default, may be "1" for Armbian
-	case "rock-pi-4":
-		return "1" // Default, may be "0" for Armbian
-	case "rock-pi-3":
-		return "15"
-	case "rock-3c":
-		return "" // Uses software PWM
-	default:
-		return "1" // Generic fallback
-	}
-}
-
 // PrintDetectionReport prints a detailed detection report
 func (d *DeviceInfo) PrintDetectionReport() {
 	fmt.Println("=== Device Detection Report ===")
@@ -447,16 +308,16 @@ func (d *DeviceInfo) PrintDetectionReport() {
 
 	fmt.Println("Detected Configuration:")
 	envVars := d.GetRecommendedEnvVars()
-	for key, value := range envVars {
-		fmt.Printf("  %s=%s\n", key, value)
+	for _, key := range EnvVarKeys(envVars) {
+		fmt.Printf("  %s=%s\n", key, envVars[key])
 	}
 	fmt.Println()
 
 	fmt.Println("Hardware Access Test:")
 	access := d.VerifyHardwareAccess()
-	for component, accessible := range access {
+	for _, component := range AccessKeys(access) {
 		status := "❌ FAIL"
-		if accessible {
+		if access[component] {
 			status = "✅ OK"
 		}
 		fmt.Printf("  %s: %s\n", component, status)
@@ -507,7 +368,7 @@ func ParseGPIOFromKernel() map[string]string {
 	info := make(map[string]string)
 
 	// Try to read from /sys/kernel/debug/gpio (requires root)
-	if data, err := os.ReadFile("/sys/kernel/debug/gpio"); err == nil {
+	if data, err := hostfs.ReadFile("/sys/kernel/debug/gpio"); err == nil {
 		lines := strings.Split(string(data), "\n")
 		currentChip := ""
 		chipRegex := regexp.MustCompile(`gpiochip(\d+):`)
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fan types, they select the default PWM frequency
+const (
+	FanType4Wire = "4-wire" // PWM input on the fan, driven at 25kHz
+	FanType2Wire = "2-wire" // Fan power switched by a transistor, driven slowly
+)
+
+const (
+	fourWireFrequency = 25000 // Intel 4-wire fan specification
+	twoWireFrequency  = 40    // Low enough for the transistor to switch cleanly
+)
+
+// PWMSettings is the PWM output driving the fan
+type PWMSettings struct {
+	Chip      string  // Hardware PWM chip number
+	Channel   int     // Hardware PWM channel on the chip
+	Frequency float64 // Hz
+}
+
+// Period returns the PWM period
+func (p PWMSettings) Period() time.Duration {
+	return time.Duration(float64(time.Second) / p.Frequency)
+}
+
+// GetPWMSettings merges the [fan] PWM settings with the environment. Values
+// set in the config file take precedence, the chip falls back to PWMCHIP and
+// then FAN_CHIP. Without an explicit frequency it follows the fan type, or
+// the PWM backend when no type is set (25kHz hardware, 40Hz software).
+func (c *Config) GetPWMSettings(hw *HardwareConfig) (PWMSettings, error) {
+	settings := PWMSettings{
+		Chip: firstNonEmpty(c.Fan.PWMChip, hw.PWMChip, hw.FanChip),
+	}
+
+	settings.Channel = c.Fan.PWMChannel
+	if settings.Channel < 0 {
+		settings.Channel = 0
+		if hw.PWMChannel != "" {
+			channel, err := strconv.Atoi(hw.PWMChannel)
+			if err != nil || channel < 0 {
+				return settings, fmt.Errorf("invalid PWM_CHANNEL %q", hw.PWMChannel)
+			}
+			settings.Channel = channel
+		}
+	}
+
+	settings.Frequency = c.Fan.PWMFrequency
+	if settings.Frequency == 0 && hw.PWMFrequency != "" {
+		frequency, err := strconv.ParseFloat(hw.PWMFrequency, 64)
+		if err != nil {
+			return settings, fmt.Errorf("invalid PWM_FREQUENCY %q", hw.PWMFrequency)
+		}
+		settings.Frequency = frequency
+	}
+	if settings.Frequency < 0 {
+		return settings, fmt.Errorf("invalid PWM frequency %.1f", settings.Frequency)
+	}
+
+	if settings.Frequency == 0 {
+		switch fanType := strings.ToLower(firstNonEmpty(c.Fan.Type, hw.FanType)); fanType {
+		case FanType4Wire:
+			settings.Frequency = fourWireFrequency
+		case FanType2Wire:
+			settings.Frequency = twoWireFrequency
+		case "":
+			settings.Frequency = twoWireFrequency
+			if hw.HardwarePWM {
+				settings.Frequency = fourWireFrequency
+			}
+		default:
+			return settings, fmt.Errorf("invalid fan type %q (expected %s or %s)", fanType, FanType4Wire, FanType2Wire)
+		}
+	}
+
+	return settings, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
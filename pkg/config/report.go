@@ -0,0 +1,91 @@
+package config
+
+import (
+	"sort"
+)
+
+// ReportSchemaVersion is bumped when a DetectionReport field is removed or
+// changes meaning; new fields may be added without a bump
+const ReportSchemaVersion = 1
+
+// envVarOrder is the order environment variables are listed in, matching
+// the shipped env file. Unknown keys follow alphabetically.
+var envVarOrder = []string{
+	"SDA", "SCL", "OLED_RESET", "I2C_BUS",
+	"BUTTON_CHIP", "BUTTON_LINE",
+	"FAN_CHIP", "FAN_LINE", "FAN_POLARITY", "HARDWARE_PWM",
+	"PWMCHIP", "PWM_CHANNEL", "PWM_FREQUENCY", "FAN_TYPE",
+}
+
+// DetectionReport is the machine-readable result of device detection
+type DetectionReport struct {
+	SchemaVersion int               `json:"schema_version"`
+	BoardType     string            `json:"board_type"`
+	Model         string            `json:"model"`
+	Confidence    int               `json:"confidence"`           // 0-100
+	Env           map[string]string `json:"env"`                  // Recommended environment variables
+	Access        map[string]bool   `json:"access"`               // Hardware access test per component
+	Verified      bool              `json:"verified"`             // Every access test passed
+	Notes         []string          `json:"notes"`                // Detection notes, in the order they were made
+	Comparison    *ConfigComparison `json:"comparison,omitempty"` // Only with -verify
+}
+
+// ConfigComparison compares the current configuration with the detected one
+type ConfigComparison struct {
+	Current     map[string]string `json:"current"`     // Configured values
+	Differences []string          `json:"differences"` // Keys where current and env disagree
+}
+
+// Report builds the detection report, running the hardware access tests
+func (d *DeviceInfo) Report() DetectionReport {
+	access := d.VerifyHardwareAccess()
+	verified := true
+	for _, ok := range access {
+		verified = verified && ok
+	}
+
+	notes := d.DetectionNotes
+	if notes == nil {
+		notes = []string{}
+	}
+
+	return DetectionReport{
+		SchemaVersion: ReportSchemaVersion,
+		BoardType:     d.BoardType,
+		Model:         d.Model,
+		Confidence:    d.Confidence,
+		Env:           d.GetRecommendedEnvVars(),
+		Access:        access,
+		Verified:      verified,
+		Notes:         notes,
+	}
+}
+
+// EnvVarKeys returns the keys of vars in a stable order
+func EnvVarKeys(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for _, key := range envVarOrder {
+		if _, ok := vars[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var extra []string
+	for key := range vars {
+		if !containsString(envVarOrder, key) {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	return append(keys, extra...)
+}
+
+// AccessKeys returns the components of an access test in a stable order
+func AccessKeys(access map[string]bool) []string {
+	keys := make([]string, 0, len(access))
+	for key := range access {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
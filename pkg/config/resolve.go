@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/gpiocdev"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hostfs"
+)
+
+// resolvePWMChip finds the pwmchip whose device is the PWM controller at the
+// given address (e.g. febf0020.pwm). Chip numbers depend on probe order and
+// change between kernels, the controller address doesn't.
+func resolvePWMChip(address string) (string, error) {
+	chips, _ := hostfs.Glob("/sys/class/pwm/pwmchip*")
+	for _, chip := range chips {
+		target, err := hostfs.Readlink(filepath.Join(chip, "device"))
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(filepath.Base(target), address) {
+			return strings.TrimPrefix(filepath.Base(chip), "pwmchip"), nil
+		}
+	}
+	return "", fmt.Errorf("no pwmchip for PWM controller %s", address)
+}
+
+// resolveHardware replaces the chip numbers of the board profile with the
+// ones found through the PWM controller address and GPIO chip label
+func resolveHardware(device *DeviceInfo, profile *BoardProfile) {
+	if device.HardwarePWM && profile.PWMController != "" {
+		if chip, err := resolvePWMChip(profile.PWMController); err == nil {
+			device.PWMChip = chip
+			device.DetectionNotes = append(device.DetectionNotes, fmt.Sprintf("PWM controller %s is pwmchip%s", profile.PWMController, chip))
+		} else {
+			device.DetectionNotes = append(device.DetectionNotes, fmt.Sprintf("%v, using pwmchip%s", err, device.PWMChip))
+		}
+	}
+
+	// Chip labels are read through ioctls, which a captured tree can't answer
+	if profile.GPIOLabel != "" && !hostfs.IsHost() {
+		device.DetectionNotes = append(device.DetectionNotes, fmt.Sprintf("GPIO chip %s not looked up below %s, using %s", profile.GPIOLabel, hostfs.Root(), device.GPIOChipPath))
+	} else if profile.GPIOLabel != "" {
+		if chip, err := gpiocdev.FindChipByLabel(profile.GPIOLabel); err == nil {
+			device.ButtonChip = chip.Number()
+			if !device.HardwarePWM {
+				device.FanChip = chip.Number()
+			}
+			device.GPIOChipPath = chip.Path
+			device.DetectionNotes = append(device.DetectionNotes, fmt.Sprintf("GPIO chip %s is %s", profile.GPIOLabel, chip.Name))
+		} else {
+			device.DetectionNotes = append(device.DetectionNotes, fmt.Sprintf("%v, using %s", err, device.GPIOChipPath))
+		}
+	}
+}
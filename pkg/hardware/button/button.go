@@ -3,30 +3,35 @@ package button
 import (
 	"fmt"
 	"log"
-	"regexp"
-	"strings"
 	"sync"
 	"time"
 
 	"periph.io/x/conn/v3/gpio"
-	"periph.io/x/conn/v3/gpio/gpioreg"
-	"periph.io/x/host/v3"
 
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/gpiocdev"
 )
 
 type Controller struct {
-	pin         gpio.PinIn
-	running     bool
-	stopCh      chan struct{}
-	eventCh     chan string
-	mutex       sync.RWMutex
-	patterns    map[string]*regexp.Regexp
-	bufferSize  int
-	waitPeriod  int
-	pressPeriod int
+	pin      gpio.PinIn
+	running  bool
+	stopCh   chan struct{}
+	eventCh  chan string
+	mutex    sync.RWMutex
+	detector *detector
 }
 
+const (
+	// debounceTime is how long level changes are ignored after an accepted edge
+	debounceTime = 20 * time.Millisecond
+	// maxWait bounds each edge wait so Stop is noticed promptly
+	maxWait = 100 * time.Millisecond
+	// idlePoll throttles the loop on pins that can't wait for edges
+	idlePoll = 10 * time.Millisecond
+	// consumer labels the button line, shown by gpioinfo
+	consumer = "rockpi-penta-button"
+)
+
 var (
 	instance *Controller
 	once     sync.Once
@@ -53,45 +58,23 @@ func (c *Controller) Initialize() error {
 		return fmt.Errorf("hardware configuration not loaded")
 	}
 
-	// Initialize periph.io
-	if _, err := host.Init(); err != nil {
-		return fmt.Errorf("failed to initialize periph.io: %v", err)
-	}
-
-	// Convert chip and line to GPIO pin name
-	pinName := fmt.Sprintf("GPIO%s_%s", hwConfig.ButtonChip, hwConfig.ButtonLine)
-	pin := gpioreg.ByName(pinName)
-	if pin == nil {
-		// Try alternative naming
-		pinName = fmt.Sprintf("GPIO%s", hwConfig.ButtonLine)
-		pin = gpioreg.ByName(pinName)
-		if pin == nil {
-			return fmt.Errorf("failed to find GPIO pin %s or %s",
-				fmt.Sprintf("GPIO%s_%s", hwConfig.ButtonChip, hwConfig.ButtonLine), pinName)
-		}
+	pin, err := gpiocdev.Open(hwConfig.ButtonChip, hwConfig.ButtonLine, consumer)
+	if err != nil {
+		return fmt.Errorf("failed to open button GPIO line: %v", err)
 	}
 
 	// Configure as input with pull-up
 	if err := pin.In(gpio.PullUp, gpio.BothEdges); err != nil {
+		pin.Close()
 		return fmt.Errorf("failed to configure GPIO pin as input: %v", err)
 	}
 
 	c.pin = pin
 
-	// Setup timing and patterns based on config
-	cfg := config.GlobalConfig
-	c.waitPeriod = int(cfg.Time.Twice * 10)  // Convert to 100ms units
-	c.pressPeriod = int(cfg.Time.Press * 10) // Convert to 100ms units
-	c.bufferSize = c.pressPeriod
-
-	// Create regex patterns for button events
-	c.patterns = map[string]*regexp.Regexp{
-		"click": regexp.MustCompile(fmt.Sprintf(`1+0+1{%d,}`, c.waitPeriod)),
-		"twice": regexp.MustCompile(`1+0+1+0+1{3,}`),
-		"press": regexp.MustCompile(fmt.Sprintf(`1+0{%d,}`, c.pressPeriod)),
-	}
+	// Setup timing based on config
+	c.detector = newDetectorFromConfig(config.GlobalConfig)
 
-	log.Printf("Button controller initialized on GPIO%s_%s", hwConfig.ButtonChip, hwConfig.ButtonLine)
+	log.Printf("Button controller initialized on %s", pin)
 	return nil
 }
 
@@ -110,6 +93,10 @@ func (c *Controller) Start() error {
 		}
 	}
 
+	if c.detector == nil {
+		c.detector = newDetectorFromConfig(config.GlobalConfig)
+	}
+
 	c.running = true
 	c.stopCh = make(chan struct{})
 
@@ -137,70 +124,77 @@ func (c *Controller) GetEventChannel() <-chan string {
 	return c.eventCh
 }
 
-// monitorLoop is the main button monitoring loop
+// monitorLoop waits for edges on the button pin and feeds the settled
+// levels to the detector. The button is active low (pull-up).
 func (c *Controller) monitorLoop() {
-	ticker := time.NewTicker(100 * time.Millisecond) // 100ms polling
-	defer ticker.Stop()
-
-	buffer := make([]string, 0, c.bufferSize)
+	c.mutex.RLock()
+	pin := c.pin
+	det := c.detector
+	c.mutex.RUnlock()
 
 	for {
 		select {
 		case <-c.stopCh:
 			return
-		case <-ticker.C:
-			// Read current pin state
-			level := gpio.High
-			if c.pin.Read() == gpio.Low {
-				level = gpio.Low
-			}
+		default:
+		}
 
-			// Convert to string (1 for high, 0 for low)
-			var stateStr string
-			if level == gpio.High {
-				stateStr = "1"
-			} else {
-				stateStr = "0"
+		// Wait until the next edge, the next detector deadline or maxWait
+		timeout := maxWait
+		c.mutex.RLock()
+		deadline := det.deadline()
+		c.mutex.RUnlock()
+		if !deadline.IsZero() {
+			if untilDeadline := time.Until(deadline); untilDeadline < timeout {
+				timeout = untilDeadline
 			}
+		}
 
-			// Add to buffer
-			buffer = append(buffer, stateStr)
-
-			// Keep buffer size manageable
-			if len(buffer) > c.bufferSize {
-				buffer = buffer[1:]
+		if timeout > 0 {
+			start := time.Now()
+			if !pin.WaitForEdge(timeout) && time.Since(start) < time.Millisecond {
+				// The pin doesn't support edge detection, fall back to polling
+				time.Sleep(idlePoll)
 			}
+		}
 
-			// Check for patterns if buffer has enough data
-			if len(buffer) >= 10 { // Minimum buffer size for pattern matching
-				bufferStr := strings.Join(buffer, "")
-				event := c.matchPattern(bufferStr)
-				if event != "" {
-					select {
-					case c.eventCh <- event:
-						// Clear buffer after detecting an event
-						buffer = buffer[:0]
-					default:
-						// Channel full, skip this event
-					}
-				}
+		// Sample the level after every wait, the detector drops bounces
+		c.mutex.Lock()
+		event := det.update(pin.Read() == gpio.Low, time.Now())
+		if event == "" {
+			event = det.poll(time.Now())
+		}
+		c.mutex.Unlock()
+
+		if event != "" {
+			log.Printf("Button event detected: %s", event)
+			select {
+			case c.eventCh <- event:
+			default:
+				// Channel full, skip this event
 			}
 		}
 	}
 }
 
-// matchPattern checks if the buffer matches any button event pattern
-func (c *Controller) matchPattern(buffer string) string {
-	// Check patterns in order of priority
-	for _, event := range []string{"press", "twice", "click"} {
-		if pattern, exists := c.patterns[event]; exists {
-			if pattern.MatchString(buffer) {
-				log.Printf("Button event detected: %s", event)
-				return event
-			}
-		}
+// newDetectorFromConfig creates a detector for the gestures bound in [key]
+// using the [time] settings
+func newDetectorFromConfig(cfg *config.Config) *detector {
+	return newDetector(gesturesFromConfig(cfg), debounceTime)
+}
+
+// gesturesFromConfig builds the gesture set from the configuration
+func gesturesFromConfig(cfg *config.Config) gestureSet {
+	gestures, unknown := newGestureSet(
+		cfg.GetBoundGestures(),
+		time.Duration(cfg.Time.Twice*float64(time.Second)),
+		time.Duration(cfg.Time.Press*float64(time.Second)),
+		time.Duration(cfg.Time.Repeat*float64(time.Second)),
+	)
+	for _, name := range unknown {
+		log.Printf("Warning: unknown button gesture %q in [key]", name)
 	}
-	return ""
+	return gestures
 }
 
 // IsRunning returns whether the button controller is running
@@ -223,15 +217,10 @@ func (c *Controller) UpdateConfig() {
 	defer c.mutex.Unlock()
 
 	cfg := config.GlobalConfig
-	c.waitPeriod = int(cfg.Time.Twice * 10)
-	c.pressPeriod = int(cfg.Time.Press * 10)
-	c.bufferSize = c.pressPeriod
-
-	// Recreate patterns with new timing
-	c.patterns = map[string]*regexp.Regexp{
-		"click": regexp.MustCompile(fmt.Sprintf(`1+0+1{%d,}`, c.waitPeriod)),
-		"twice": regexp.MustCompile(`1+0+1+0+1{3,}`),
-		"press": regexp.MustCompile(fmt.Sprintf(`1+0{%d,}`, c.pressPeriod)),
+	if c.detector == nil {
+		c.detector = newDetectorFromConfig(cfg)
+	} else {
+		c.detector.gestures = gesturesFromConfig(cfg)
 	}
 
 	log.Printf("Button timing updated: twice=%.1fs, press=%.1fs",
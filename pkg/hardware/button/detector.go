@@ -0,0 +1,240 @@
+package button
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clickNames are the gesture names of 1, 2 and 3 clicks; more clicks are "clicks-N"
+var clickNames = []string{"", "click", "twice", "triple"}
+
+// pressThreshold is a long press gesture that fires after holding for a given time
+type pressThreshold struct {
+	name  string
+	after time.Duration
+}
+
+// gestureSet describes which gestures the detector has to recognise
+type gestureSet struct {
+	twice    time.Duration    // Maximum gap between consecutive clicks
+	maxClick int              // Highest click count that is bound
+	presses  []pressThreshold // Sorted by ascending hold time
+	repeat   time.Duration    // Interval of "hold" events, 0 when unbound
+	holdFrom time.Duration    // Hold time of the first "hold" event
+}
+
+// clickName returns the gesture name of n consecutive clicks
+func clickName(n int) string {
+	if n < len(clickNames) {
+		return clickNames[n]
+	}
+	return fmt.Sprintf("clicks-%d", n)
+}
+
+// newGestureSet builds the gesture set for the bound gesture names. The
+// click, twice and press gestures are always recognised since the menu relies
+// on them, except that a bound "hold" takes the place of "press": it starts
+// at the press time, and firing both would run two actions for one hold.
+func newGestureSet(names []string, twice, press, repeat time.Duration) (gestureSet, []string) {
+	set := gestureSet{
+		twice:    twice,
+		maxClick: 2,
+		holdFrom: press,
+	}
+
+	var unknown []string
+	for _, name := range names {
+		switch {
+		case name == "click" || name == "twice" || name == "press":
+			// Always recognised
+		case name == "triple":
+			set.maxClick = max(set.maxClick, 3)
+		case name == "hold":
+			set.repeat = repeat
+		case strings.HasPrefix(name, "clicks-"):
+			n, err := strconv.Atoi(strings.TrimPrefix(name, "clicks-"))
+			if err != nil || n < 1 {
+				unknown = append(unknown, name)
+				continue
+			}
+			set.maxClick = max(set.maxClick, n)
+		case strings.HasPrefix(name, "press-"):
+			seconds, err := strconv.ParseFloat(strings.TrimPrefix(name, "press-"), 64)
+			if err != nil || seconds <= 0 {
+				unknown = append(unknown, name)
+				continue
+			}
+			set.presses = append(set.presses, pressThreshold{
+				name:  name,
+				after: time.Duration(seconds * float64(time.Second)),
+			})
+		default:
+			unknown = append(unknown, name)
+		}
+	}
+
+	if set.repeat <= 0 {
+		set.presses = append(set.presses, pressThreshold{name: "press", after: press})
+	}
+	sort.Slice(set.presses, func(i, j int) bool {
+		return set.presses[i].after < set.presses[j].after
+	})
+
+	return set, unknown
+}
+
+// detector turns debounced button level changes into gesture events.
+// It holds no hardware state so it can be driven by synthetic edge sequences.
+//
+// Clicks are counted until no further press arrives within the twice window
+// (or the highest bound count is reached). The longest press gesture fires as
+// soon as it is reached, shorter ones fire on release so a long hold never
+// triggers them by accident. When bound, "hold" repeats every repeat interval
+// from the press time on, regardless of the press-<seconds> gestures; once it
+// has fired the release produces no gesture.
+//
+// Level changes within the debounce time of the last accepted one are contact
+// bounce and ignored; deadline asks for a fresh sample once it has passed.
+type detector struct {
+	gestures gestureSet
+	debounce time.Duration
+
+	lastEdge    time.Time
+	bouncing    bool
+	pressed     bool
+	pressStart  time.Time
+	releaseTime time.Time
+	clicks      int
+	longFired   bool
+	held        bool
+	nextRepeat  time.Time
+}
+
+// newDetector creates a detector for the given gesture set and debounce time
+func newDetector(gestures gestureSet, debounce time.Duration) *detector {
+	return &detector{
+		gestures: gestures,
+		debounce: debounce,
+	}
+}
+
+// update feeds a sampled level at the given time and returns the resulting
+// event, or "" if none
+func (d *detector) update(pressed bool, at time.Time) string {
+	if pressed == d.pressed {
+		d.bouncing = false
+		return ""
+	}
+	if !d.lastEdge.IsZero() && at.Sub(d.lastEdge) < d.debounce {
+		d.bouncing = true
+		return ""
+	}
+	d.bouncing = false
+	d.lastEdge = at
+	d.pressed = pressed
+
+	if pressed {
+		d.pressStart = at
+		d.longFired = false
+		d.held = false
+		d.nextRepeat = at.Add(d.gestures.holdFrom)
+		return ""
+	}
+
+	// Released: the longest press gesture or a hold already fired while held
+	if d.longFired || d.held {
+		d.longFired = false
+		d.held = false
+		d.clicks = 0
+		return ""
+	}
+
+	// Released after a long press: fire the longest threshold reached
+	if event := d.reachedPress(at.Sub(d.pressStart)); event != "" {
+		d.clicks = 0
+		return event
+	}
+
+	d.clicks++
+	d.releaseTime = at
+	if d.clicks >= d.gestures.maxClick {
+		event := clickName(d.clicks)
+		d.clicks = 0
+		return event
+	}
+	return ""
+}
+
+// poll handles time based transitions and returns the resulting event, or "" if none
+func (d *detector) poll(now time.Time) string {
+	if d.pressed {
+		if longest, ok := d.longestPress(); ok && !d.longFired && now.Sub(d.pressStart) >= longest.after {
+			d.longFired = true
+			d.clicks = 0
+			return longest.name
+		}
+
+		if d.gestures.repeat > 0 && !now.Before(d.nextRepeat) {
+			d.nextRepeat = now.Add(d.gestures.repeat)
+			d.held = true
+			d.clicks = 0
+			return "hold"
+		}
+		return ""
+	}
+
+	if d.clicks > 0 && now.Sub(d.releaseTime) >= d.gestures.twice {
+		event := clickName(d.clicks)
+		d.clicks = 0
+		return event
+	}
+	return ""
+}
+
+// deadline returns when poll should next be called, or the zero time if
+// nothing is pending
+func (d *detector) deadline() time.Time {
+	var next time.Time
+	earliest := func(t time.Time) {
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+
+	if d.bouncing {
+		earliest(d.lastEdge.Add(d.debounce))
+	}
+	if d.pressed {
+		if longest, ok := d.longestPress(); ok && !d.longFired {
+			earliest(d.pressStart.Add(longest.after))
+		}
+		if d.gestures.repeat > 0 {
+			earliest(d.nextRepeat)
+		}
+	} else if d.clicks > 0 {
+		earliest(d.releaseTime.Add(d.gestures.twice))
+	}
+	return next
+}
+
+// longestPress returns the press gesture with the longest hold time, if any
+func (d *detector) longestPress() (pressThreshold, bool) {
+	if len(d.gestures.presses) == 0 {
+		return pressThreshold{}, false
+	}
+	return d.gestures.presses[len(d.gestures.presses)-1], true
+}
+
+// reachedPress returns the longest press gesture shorter than the held time
+func (d *detector) reachedPress(held time.Duration) string {
+	event := ""
+	for _, threshold := range d.gestures.presses {
+		if held >= threshold.after {
+			event = threshold.name
+		}
+	}
+	return event
+}
@@ -0,0 +1,209 @@
+package button
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// edge is a sampled level change at a time after the first press
+type edge struct {
+	at      time.Duration
+	pressed bool
+}
+
+// down and up build edges at the given milliseconds
+func down(ms int) edge { return edge{at: time.Duration(ms) * time.Millisecond, pressed: true} }
+func up(ms int) edge   { return edge{at: time.Duration(ms) * time.Millisecond, pressed: false} }
+
+// run feeds the edges to a detector and, like the monitor loop, samples the
+// level and polls at every deadline in between. It returns the events in order.
+func run(t *testing.T, d *detector, edges []edge) []string {
+	t.Helper()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []string{}
+	level := false
+
+	pollUntil := func(end time.Time) {
+		for i := 0; ; i++ {
+			if i > 1000 {
+				t.Fatal("detector keeps requesting polls")
+			}
+			deadline := d.deadline()
+			if deadline.IsZero() || deadline.After(end) {
+				return
+			}
+			if event := d.update(level, deadline); event != "" {
+				events = append(events, event)
+			}
+			if event := d.poll(deadline); event != "" {
+				events = append(events, event)
+			}
+		}
+	}
+
+	for _, e := range edges {
+		at := start.Add(e.at)
+		pollUntil(at)
+		level = e.pressed
+		if event := d.update(e.pressed, at); event != "" {
+			events = append(events, event)
+		}
+	}
+	// Let pending clicks time out; a still held button is cut off after a minute
+	pollUntil(start.Add(time.Minute))
+	return events
+}
+
+func TestDetector(t *testing.T) {
+	tests := []struct {
+		name  string
+		bound []string
+		edges []edge
+		want  []string
+	}{
+		{
+			name:  "click",
+			edges: []edge{down(0), up(100)},
+			want:  []string{"click"},
+		},
+		{
+			name:  "double click",
+			edges: []edge{down(0), up(100), down(300), up(400)},
+			want:  []string{"twice"},
+		},
+		{
+			name:  "clicks too far apart",
+			edges: []edge{down(0), up(100), down(900), up(1000)},
+			want:  []string{"click", "click"},
+		},
+		{
+			name:  "triple click",
+			bound: []string{"triple"},
+			edges: []edge{down(0), up(100), down(300), up(400), down(600), up(700)},
+			want:  []string{"triple"},
+		},
+		{
+			name:  "double click with triple bound waits for the window",
+			bound: []string{"triple"},
+			edges: []edge{down(0), up(100), down(300), up(400)},
+			want:  []string{"twice"},
+		},
+		{
+			name:  "clicks-N",
+			bound: []string{"clicks-4"},
+			edges: []edge{down(0), up(100), down(300), up(400), down(600), up(700), down(900), up(1000)},
+			want:  []string{"clicks-4"},
+		},
+		{
+			name:  "bounces on press and release count as one click",
+			edges: []edge{down(0), up(3), down(7), up(100), down(104), up(109)},
+			want:  []string{"click"},
+		},
+		{
+			name:  "bounce settling at the other level is sampled after the debounce time",
+			edges: []edge{down(0), up(10)},
+			want:  []string{"click"},
+		},
+		{
+			name:  "edges past the debounce time are separate clicks",
+			edges: []edge{down(0), up(30), down(60), up(90)},
+			want:  []string{"twice"},
+		},
+		{
+			name:  "release before press threshold is a click",
+			edges: []edge{down(0), up(1700)},
+			want:  []string{"click"},
+		},
+		{
+			name:  "press fires while held",
+			edges: []edge{down(0), up(2500)},
+			want:  []string{"press"},
+		},
+		{
+			name:  "press fires on release below a longer threshold",
+			bound: []string{"press-10"},
+			edges: []edge{down(0), up(3000)},
+			want:  []string{"press"},
+		},
+		{
+			name:  "longest threshold fires while held",
+			bound: []string{"press-10"},
+			edges: []edge{down(0), up(11000)},
+			want:  []string{"press-10"},
+		},
+		{
+			name:  "hold replaces press",
+			bound: []string{"hold"},
+			edges: []edge{down(0), up(2600)},
+			want:  []string{"hold", "hold"},
+		},
+		{
+			name:  "hold bound, release before the press time is a click",
+			bound: []string{"hold"},
+			edges: []edge{down(0), up(1700)},
+			want:  []string{"click"},
+		},
+		{
+			name:  "release after hold fires nothing",
+			bound: []string{"hold", "press-10"},
+			edges: []edge{down(0), up(3000)},
+			want:  []string{"hold", "hold", "hold"},
+		},
+		{
+			name:  "hold keeps repeating past the longest threshold",
+			bound: []string{"hold", "press-3"},
+			edges: []edge{down(0), up(3400)},
+			want:  []string{"hold", "hold", "hold", "press-3", "hold"},
+		},
+		{
+			name:  "click after hold",
+			bound: []string{"hold"},
+			edges: []edge{down(0), up(2000), down(3000), up(3100)},
+			want:  []string{"hold", "click"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gestures, unknown := newGestureSet(tt.bound, 700*time.Millisecond, 1800*time.Millisecond, 500*time.Millisecond)
+			if len(unknown) > 0 {
+				t.Fatalf("unknown gestures %v", unknown)
+			}
+			got := run(t, newDetector(gestures, 20*time.Millisecond), tt.edges)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGestureSetUnknown(t *testing.T) {
+	_, unknown := newGestureSet([]string{"click", "clicks-0", "press-x", "wiggle"}, time.Second, time.Second, time.Second)
+	want := []string{"clicks-0", "press-x", "wiggle"}
+	if !reflect.DeepEqual(unknown, want) {
+		t.Errorf("unknown = %v, want %v", unknown, want)
+	}
+}
+
+func TestDetectorDebounceDeadline(t *testing.T) {
+	gestures, _ := newGestureSet(nil, 700*time.Millisecond, 1800*time.Millisecond, 0)
+	d := newDetector(gestures, 20*time.Millisecond)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d.update(true, start)
+	if event := d.update(false, start.Add(5*time.Millisecond)); event != "" {
+		t.Errorf("bounce produced %q", event)
+	}
+	if want := start.Add(20 * time.Millisecond); !d.deadline().Equal(want) {
+		t.Errorf("deadline = %v, want the end of the debounce time %v", d.deadline(), want)
+	}
+	if !d.pressed {
+		t.Error("bounce was taken as a release")
+	}
+
+	d.update(false, start.Add(20*time.Millisecond))
+	if d.pressed {
+		t.Error("release after the debounce time was ignored")
+	}
+}
@@ -0,0 +1,318 @@
+package disk
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+// PowerState is the ATA power mode of a disk
+type PowerState int
+
+const (
+	StateUnknown PowerState = iota
+	StateActive
+	StateIdle
+	StateStandby
+)
+
+// String returns the name of the power state
+func (p PowerState) String() string {
+	switch p {
+	case StateActive:
+		return "active"
+	case StateIdle:
+		return "idle"
+	case StateStandby:
+		return "standby"
+	default:
+		return "unknown"
+	}
+}
+
+// diskState tracks I/O activity, power state and temperature of one disk
+type diskState struct {
+	ioCount uint64
+	lastIO  time.Time
+	power   PowerState
+	temp    float64
+	hasTemp bool
+}
+
+type Controller struct {
+	disks   map[string]*diskState
+	running bool
+	stopCh  chan struct{}
+	mutex   sync.RWMutex
+}
+
+var (
+	instance *Controller
+	once     sync.Once
+)
+
+// GetInstance returns the singleton disk power controller
+func GetInstance() *Controller {
+	once.Do(func() {
+		instance = &Controller{
+			disks:  make(map[string]*diskState),
+			stopCh: make(chan struct{}),
+		}
+	})
+	return instance
+}
+
+// Start begins tracking disk activity and spinning down idle disks
+func (c *Controller) Start() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.running {
+		return fmt.Errorf("disk controller already running")
+	}
+
+	c.running = true
+	c.stopCh = make(chan struct{})
+
+	go c.monitorLoop()
+	log.Println("Disk power controller started")
+	return nil
+}
+
+// Stop stops the disk monitoring
+func (c *Controller) Stop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.running {
+		return
+	}
+
+	c.running = false
+	close(c.stopCh)
+	log.Println("Disk power controller stopped")
+}
+
+// monitorLoop periodically checks disk activity
+func (c *Controller) monitorLoop() {
+	interval := time.Duration(config.GlobalConfig.Disk.Interval * float64(time.Second))
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	c.update()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			c.update()
+		}
+	}
+}
+
+// probeResult is the power state and temperature read from one disk
+type probeResult struct {
+	power   PowerState
+	temp    float64
+	hasTemp bool
+}
+
+// update refreshes I/O counters and power states and spins down disks idle
+// for longer than their configured standby time. The ATA commands can take
+// seconds, so they run without holding the lock.
+func (c *Controller) update() {
+	counters, err := readIOCounters()
+	if err != nil {
+		log.Printf("Failed to read disk statistics: %v", err)
+		return
+	}
+
+	now := time.Now()
+	devices := config.GlobalConfig.GetDiskDevices()
+
+	c.mutex.Lock()
+	present := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		present[device] = true
+
+		state, exists := c.disks[device]
+		if !exists {
+			state = &diskState{ioCount: counters[device], lastIO: now}
+			c.disks[device] = state
+		}
+		if counters[device] != state.ioCount {
+			state.ioCount = counters[device]
+			state.lastIO = now
+		}
+	}
+
+	// Forget disks that were removed
+	for device := range c.disks {
+		if !present[device] {
+			delete(c.disks, device)
+		}
+	}
+	c.mutex.Unlock()
+
+	results := make(map[string]probeResult, len(devices))
+	for _, device := range devices {
+		var result probeResult
+
+		// CHECK POWER MODE is answered without spinning the disk up
+		if power, err := checkPowerMode(device); err == nil {
+			result.power = power
+		} else {
+			result.power = StateUnknown
+		}
+
+		// Reading the temperature may wake the disk, keep the last value while it sleeps
+		if result.power != StateStandby {
+			if temp, err := readDriveTemp(device); err == nil {
+				result.temp = temp
+				result.hasTemp = true
+			}
+		}
+		results[device] = result
+	}
+
+	c.mutex.Lock()
+	idle := make(map[string]time.Duration)
+	for device, result := range results {
+		state, exists := c.disks[device]
+		if !exists {
+			continue
+		}
+		state.power = result.power
+		if result.hasTemp {
+			state.temp = result.temp
+			state.hasTemp = true
+		}
+
+		timeout := config.GlobalConfig.Disk.StandbyTimeout(device)
+		if timeout <= 0 || state.power == StateStandby || state.power == StateUnknown {
+			continue
+		}
+		if idleFor := now.Sub(state.lastIO); idleFor >= timeout {
+			idle[device] = idleFor
+		}
+	}
+	c.mutex.Unlock()
+
+	for device, idleFor := range idle {
+		if err := c.Standby(device); err != nil {
+			log.Printf("Failed to spin down %s: %v", device, err)
+			continue
+		}
+		log.Printf("Disk %s idle for %s, spun down", device, idleFor.Round(time.Second))
+	}
+}
+
+// Standby spins a disk down immediately
+func (c *Controller) Standby(device string) error {
+	if err := standby(device); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	if state, exists := c.disks[device]; exists {
+		state.power = StateStandby
+	}
+	c.mutex.Unlock()
+	return nil
+}
+
+// GetPowerState returns the last known power state of a disk
+func (c *Controller) GetPowerState(device string) PowerState {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if state, exists := c.disks[device]; exists {
+		return state.power
+	}
+	return StateUnknown
+}
+
+// IsSleeping returns whether a disk is known to be in standby
+func (c *Controller) IsSleeping(device string) bool {
+	return c.GetPowerState(device) == StateStandby
+}
+
+// GetPowerStates returns the last known power state of every tracked disk
+func (c *Controller) GetPowerStates() map[string]PowerState {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	states := make(map[string]PowerState, len(c.disks))
+	for device, state := range c.disks {
+		states[device] = state.power
+	}
+	return states
+}
+
+// AllStandby reports whether every tracked disk is in standby. It checks
+// /proc/diskstats on each call, so a disk that just spun up for I/O is
+// noticed immediately rather than at the next periodic update.
+func (c *Controller) AllStandby() bool {
+	counters, err := readIOCounters()
+	if err != nil {
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.disks) == 0 {
+		return false
+	}
+
+	allStandby := true
+	for device, state := range c.disks {
+		if counters[device] != state.ioCount {
+			// I/O since the last check means the disk is spinning again
+			state.ioCount = counters[device]
+			state.lastIO = time.Now()
+			if state.power == StateStandby {
+				state.power = StateActive
+			}
+		}
+		if state.power != StateStandby {
+			allStandby = false
+		}
+	}
+	return allStandby
+}
+
+// GetMaxTemperature returns the highest temperature of the disks that are
+// awake, or false if none reported one
+func (c *Controller) GetMaxTemperature() (float64, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	maxTemp := 0.0
+	found := false
+	for _, state := range c.disks {
+		if state.power == StateStandby || !state.hasTemp {
+			continue
+		}
+		if !found || state.temp > maxTemp {
+			maxTemp = state.temp
+			found = true
+		}
+	}
+	return maxTemp, found
+}
+
+// IsRunning returns whether the disk controller is running
+func (c *Controller) IsRunning() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.running
+}
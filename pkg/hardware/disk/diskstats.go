@@ -0,0 +1,40 @@
+package disk
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readIOCounters returns the completed read+write requests per device from /proc/diskstats
+func readIOCounters() (map[string]uint64, error) {
+	file, err := os.Open("/proc/diskstats")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseDiskstats(bufio.NewScanner(file)), nil
+}
+
+// parseDiskstats parses /proc/diskstats lines:
+// major minor name reads merged sectors ms writes ...
+func parseDiskstats(scanner *bufio.Scanner) map[string]uint64 {
+	counters := make(map[string]uint64)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		reads, err1 := strconv.ParseUint(fields[3], 10, 64)
+		writes, err2 := strconv.ParseUint(fields[7], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		counters[fields[2]] = reads + writes
+	}
+
+	return counters
+}
@@ -0,0 +1,64 @@
+package disk
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiskstats(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines string
+		want  map[string]uint64
+	}{
+		{
+			name: "kernel 5.5+ with discard and flush fields",
+			lines: `   1       0 ram0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+ 179       0 mmcblk0 38152 11962 2418166 37946 102388 121406 4283728 429588 0 196840 482418 0 0 0 0 7480 14883
+ 179       1 mmcblk0p1 237 1117 14926 291 2 0 2 3 0 148 294 0 0 0 0 0 0
+   8       0 sda 24593 1203 1884482 201775 8816 7302 501672 93431 0 99656 310416 0 0 0 0 862 15209
+   8       1 sda1 24468 1203 1879938 201561 8816 7302 501672 93431 0 99468 294992 0 0 0 0 0 0
+   8      16 sdb 12 0 96 4 0 0 0 0 0 16 4 0 0 0 0 0 0
+`,
+			want: map[string]uint64{
+				"ram0":      0,
+				"mmcblk0":   38152 + 102388,
+				"mmcblk0p1": 237 + 2,
+				"sda":       24593 + 8816,
+				"sda1":      24468 + 8816,
+				"sdb":       12,
+			},
+		},
+		{
+			name: "kernel 4.x with 14 fields",
+			lines: `   8       0 sda 171 0 10720 1280 5 1 48 10 0 1180 1290
+   8      32 sdc 4294967295 0 0 0 18446744073709551615 0 0 0 0 0 0
+`,
+			want: map[string]uint64{
+				"sda": 171 + 5,
+				// Counters wrap, the sum does too and still differs between reads
+				"sdc": 4294967294, // 4294967295 + 18446744073709551615
+			},
+		},
+		{
+			name: "malformed lines are skipped",
+			lines: `
+   8       0 sda 1 2 3
+   8      16 sdb x 0 0 0 1 0 0 0 0 0 0
+   8      32 sdc 5 0 0 0 6 0 0 0 0 0 0
+`,
+			want: map[string]uint64{"sdc": 11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseDiskstats(bufio.NewScanner(strings.NewReader(tt.lines)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDiskstats() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package disk
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// SCSI generic ioctl and ATA PASS-THROUGH(16) constants
+const (
+	sgIO           = 0x2285
+	sgDxferNone    = -1
+	sgInterfaceID  = 'S'
+	ataPassThru16  = 0x85
+	ataProtoNoData = 3 << 1
+	ataCheckCond   = 0x20 // CK_COND: return the ATA registers in the sense data
+	ataStandbyNow  = 0xE0
+	ataCheckPower  = 0xE5
+	sgTimeoutMs    = 15000
+	senseBufferLen = 32
+)
+
+// sgIOHdr mirrors struct sg_io_hdr from <scsi/sg.h>
+type sgIOHdr struct {
+	interfaceID    int32
+	dxferDirection int32
+	cmdLen         uint8
+	mxSbLen        uint8
+	iovecCount     uint16
+	dxferLen       uint32
+	dxferp         uintptr
+	cmdp           uintptr
+	sbp            uintptr
+	timeout        uint32
+	flags          uint32
+	packID         int32
+	usrPtr         uintptr
+	status         uint8
+	maskedStatus   uint8
+	msgStatus      uint8
+	sbLenWr        uint8
+	hostStatus     uint16
+	driverStatus   uint16
+	resid          int32
+	duration       uint32
+	info           uint32
+}
+
+// ataNonData sends a non-data ATA command through SG_IO and returns the sense buffer
+func ataNonData(device string, command byte, checkCondition bool) ([]byte, error) {
+	file, err := os.OpenFile("/dev/"+device, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cdb := make([]byte, 16)
+	cdb[0] = ataPassThru16
+	cdb[1] = ataProtoNoData
+	if checkCondition {
+		cdb[2] = ataCheckCond
+	}
+	cdb[14] = command
+
+	sense := make([]byte, senseBufferLen)
+	hdr := sgIOHdr{
+		interfaceID:    sgInterfaceID,
+		dxferDirection: sgDxferNone,
+		cmdLen:         uint8(len(cdb)),
+		mxSbLen:        uint8(len(sense)),
+		cmdp:           uintptr(unsafe.Pointer(&cdb[0])),
+		sbp:            uintptr(unsafe.Pointer(&sense[0])),
+		timeout:        sgTimeoutMs,
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), sgIO, uintptr(unsafe.Pointer(&hdr)))
+	// The header only holds the buffers as uintptr, keep them alive until the ioctl returned
+	runtime.KeepAlive(cdb)
+	runtime.KeepAlive(sense)
+	if errno != 0 {
+		return nil, fmt.Errorf("SG_IO on %s failed: %v", device, errno)
+	}
+	if hdr.hostStatus != 0 || (hdr.driverStatus&^0x08) != 0 {
+		return nil, fmt.Errorf("ATA command 0x%02X on %s failed: host=0x%x driver=0x%x", command, device, hdr.hostStatus, hdr.driverStatus)
+	}
+
+	return sense[:hdr.sbLenWr], nil
+}
+
+// standby issues ATA STANDBY IMMEDIATE, spinning the disk down
+func standby(device string) error {
+	_, err := ataNonData(device, ataStandbyNow, false)
+	return err
+}
+
+// checkPowerMode issues ATA CHECK POWER MODE, which doesn't wake a sleeping disk
+func checkPowerMode(device string) (PowerState, error) {
+	sense, err := ataNonData(device, ataCheckPower, true)
+	if err != nil {
+		return StateUnknown, err
+	}
+
+	count, ok := senseSectorCount(sense)
+	if !ok {
+		return StateUnknown, fmt.Errorf("no ATA registers in sense data from %s", device)
+	}
+	return powerStateFromCount(count), nil
+}
+
+// senseSectorCount extracts the ATA sector count register from the sense data
+func senseSectorCount(sense []byte) (byte, bool) {
+	if len(sense) < 8 {
+		return 0, false
+	}
+
+	switch sense[0] & 0x7F {
+	case 0x72:
+		// Descriptor format with an ATA Status Return descriptor
+		if len(sense) >= 14 && sense[8] == 0x09 {
+			return sense[13], true
+		}
+	case 0x70:
+		// Fixed format, registers in the information field
+		return sense[6], true
+	}
+	return 0, false
+}
+
+// powerStateFromCount maps the CHECK POWER MODE result to a power state
+func powerStateFromCount(count byte) PowerState {
+	switch count {
+	case 0x00, 0x01:
+		return StateStandby
+	case 0x40, 0x41, 0x80, 0x81, 0x82, 0x83:
+		return StateIdle
+	case 0xFF:
+		return StateActive
+	default:
+		return StateUnknown
+	}
+}
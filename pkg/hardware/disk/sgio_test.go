@@ -0,0 +1,121 @@
+package disk
+
+import "testing"
+
+func TestSenseSectorCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		sense []byte
+		count byte
+		ok    bool
+	}{
+		{
+			name: "descriptor format, active",
+			// RECOVERED ERROR, ATA PASS-THROUGH INFORMATION AVAILABLE,
+			// ATA Status Return descriptor with sector count 0xff
+			sense: []byte{
+				0x72, 0x01, 0x00, 0x1d, 0x00, 0x00, 0x00, 0x0e,
+				0x09, 0x0c, 0x00, 0x00, 0x00, 0xff, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x40, 0x50,
+			},
+			count: 0xff,
+			ok:    true,
+		},
+		{
+			name: "descriptor format, standby",
+			sense: []byte{
+				0x72, 0x01, 0x00, 0x1d, 0x00, 0x00, 0x00, 0x0e,
+				0x09, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x40, 0x50,
+			},
+			count: 0x00,
+			ok:    true,
+		},
+		{
+			name: "deferred descriptor format",
+			sense: []byte{
+				0xf3, 0x01, 0x00, 0x1d, 0x00, 0x00, 0x00, 0x0e,
+				0x09, 0x0c, 0x00, 0x00, 0x00, 0x80, 0x00, 0x00,
+			},
+			ok: false,
+		},
+		{
+			name: "descriptor format, other descriptor",
+			sense: []byte{
+				0x72, 0x05, 0x24, 0x00, 0x00, 0x00, 0x00, 0x08,
+				0x02, 0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			ok: false,
+		},
+		{
+			name:  "descriptor format, truncated",
+			sense: []byte{0x72, 0x01, 0x00, 0x1d, 0x00, 0x00, 0x00, 0x0e, 0x09, 0x0c, 0x00, 0x00, 0x00},
+			ok:    false,
+		},
+		{
+			name: "fixed format, idle",
+			// Error, status, device and sector count in the information field
+			sense: []byte{
+				0x70, 0x00, 0x01, 0x00, 0x50, 0x00, 0x80, 0x0a,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x1d, 0x00, 0x00,
+				0x00, 0x00,
+			},
+			count: 0x80,
+			ok:    true,
+		},
+		{
+			name: "fixed format with valid bit, standby",
+			sense: []byte{
+				0xf0, 0x00, 0x01, 0x00, 0x50, 0x00, 0x00, 0x0a,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x1d, 0x00, 0x00,
+				0x00, 0x00,
+			},
+			count: 0x00,
+			ok:    true,
+		},
+		{
+			name:  "too short",
+			sense: []byte{0x70, 0x00, 0x01},
+			ok:    false,
+		},
+		{
+			name:  "no sense data",
+			sense: nil,
+			ok:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, ok := senseSectorCount(tt.sense)
+			if ok != tt.ok || (ok && count != tt.count) {
+				t.Errorf("senseSectorCount() = 0x%02x, %t, want 0x%02x, %t", count, ok, tt.count, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPowerStateFromCount(t *testing.T) {
+	tests := []struct {
+		count byte
+		want  PowerState
+	}{
+		{0x00, StateStandby},
+		{0x01, StateStandby},
+		{0x40, StateIdle},
+		{0x41, StateIdle},
+		{0x80, StateIdle},
+		{0x81, StateIdle},
+		{0x82, StateIdle},
+		{0x83, StateIdle},
+		{0xff, StateActive},
+		{0x02, StateUnknown},
+		{0x7f, StateUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := powerStateFromCount(tt.count); got != tt.want {
+			t.Errorf("powerStateFromCount(0x%02x) = %s, want %s", tt.count, got, tt.want)
+		}
+	}
+}
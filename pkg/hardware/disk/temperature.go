@@ -0,0 +1,28 @@
+package disk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readDriveTemp reads the disk temperature exposed by the drivetemp hwmon driver
+func readDriveTemp(device string) (float64, error) {
+	paths, err := filepath.Glob(fmt.Sprintf("/sys/block/%s/device/hwmon/hwmon*/temp1_input", device))
+	if err != nil || len(paths) == 0 {
+		return 0, fmt.Errorf("no drivetemp sensor for %s", device)
+	}
+
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		return 0, err
+	}
+
+	milliC, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, err
+	}
+	return milliC / 1000.0, nil
+}
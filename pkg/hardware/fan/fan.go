@@ -3,16 +3,17 @@ package fan
 import (
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"periph.io/x/conn/v3/gpio"
-	"periph.io/x/conn/v3/gpio/gpioreg"
-	"periph.io/x/host/v3"
-
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/disk"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/gpiocdev"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hostfs"
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/sysinfo"
 )
 
@@ -20,14 +21,51 @@ type Controller struct {
 	pwm       PWMInterface
 	lastDuty  float64
 	lastTemp  float64
-	tempCache time.Time
+	filter    *tempFilter
+	fullSpeed bool
+	quiet     bool
+	profile   string
+	failures  int
+	lastRamp  time.Time
 	running   bool
-	stopCh    chan struct{}
-	mutex     sync.RWMutex
+
+	// Full speed forced by overheat protection, independent of the mode
+	forcedFull bool
+
+	// Manual override, a fixed fan power until overrideUntil
+	override      float64
+	overrideUntil time.Time
+	overridden    bool
+	stopCh        chan struct{}
+	doneCh        chan struct{} // Closed when the control loop has exited
+	mutex         sync.RWMutex
+}
+
+// Mode selects how the fan duty cycle is chosen
+type Mode int
+
+const (
+	ModeAuto Mode = iota // Follow the temperature thresholds
+	ModeOff              // Fan stopped
+	ModeFull             // Fan at 100% regardless of temperature
+)
+
+// String returns the display name of the mode
+func (m Mode) String() string {
+	switch m {
+	case ModeOff:
+		return "off"
+	case ModeFull:
+		return "100%"
+	default:
+		return "auto"
+	}
 }
 
+// PWMInterface drives the fan, duty cycles are fan power in percent and
+// each implementation maps them to pin levels for the configured polarity
 type PWMInterface interface {
-	SetDutyCycle(duty float64) error
+	SetDutyCycle(power float64) error
 	Close() error
 }
 
@@ -35,17 +73,11 @@ type PWMInterface interface {
 type HardwarePWM struct {
 	chipPath string
 	period   time.Duration
+	polarity config.FanPolarity // Applied in software when sysfs can't
 }
 
-// SoftwarePWM represents software PWM control using GPIO
-type SoftwarePWM struct {
-	pin     gpio.PinOut
-	period  time.Duration
-	duty    float64
-	stopCh  chan struct{}
-	running bool
-	mutex   sync.RWMutex
-}
+// Consumer label of the software PWM line, shown by gpioinfo
+const consumer = "rockpi-penta-fan"
 
 var (
 	instance *Controller
@@ -77,31 +109,57 @@ func (c *Controller) Initialize() error {
 		return fmt.Errorf("hardware configuration not loaded")
 	}
 
-	var err error
+	settings, err := config.GlobalConfig.GetPWMSettings(hwConfig)
+	if err != nil {
+		return err
+	}
+
 	if hwConfig.HardwarePWM {
-		c.pwm, err = c.initHardwarePWM(hwConfig.FanChip)
+		c.pwm, err = OpenHardwarePWM(settings, hwConfig.FanPolarity)
 	} else {
-		c.pwm, err = c.initSoftwarePWM(hwConfig.FanChip, hwConfig.FanLine)
+		c.pwm, err = c.initSoftwarePWM(hwConfig.FanChip, hwConfig.FanLine, settings.Frequency, hwConfig.FanPolarity)
 	}
 
 	return err
 }
 
-func (c *Controller) initHardwarePWM(chipStr string) (*HardwarePWM, error) {
-	chipPath := fmt.Sprintf("/sys/class/pwm/pwmchip%s/pwm0/", chipStr)
+// OpenHardwarePWM exports, configures and enables a sysfs PWM channel
+func OpenHardwarePWM(settings config.PWMSettings, polarity config.FanPolarity) (*HardwarePWM, error) {
+	basePath := hostfs.Path("/sys/class/pwm/pwmchip"+settings.Chip) + "/"
+
+	// Validate the channel against the number of channels of the chip
+	data, err := os.ReadFile(basePath + "npwm")
+	if err != nil {
+		return nil, fmt.Errorf("PWM chip %s not available: %v", settings.Chip, err)
+	}
+	npwm, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read channel count of PWM chip %s: %v", settings.Chip, err)
+	}
+	if settings.Channel >= npwm {
+		return nil, fmt.Errorf("PWM channel %d out of range, pwmchip%s has %d channel(s)", settings.Channel, settings.Chip, npwm)
+	}
+
+	channel := strconv.Itoa(settings.Channel)
+	chipPath := basePath + "pwm" + channel + "/"
 
 	// Try to export PWM
-	exportPath := fmt.Sprintf("/sys/class/pwm/pwmchip%s/export", chipStr)
-	if err := os.WriteFile(exportPath, []byte("0"), 0644); err != nil {
+	if err := os.WriteFile(basePath+"export", []byte(channel), 0644); err != nil {
 		// Ignore error if already exported
 		log.Printf("Warning: PWM export error (may already be exported): %v", err)
 	}
 
 	pwm := &HardwarePWM{
 		chipPath: chipPath,
-		period:   40 * time.Microsecond, // 25kHz frequency
+		period:   settings.Period(),
+		polarity: polarity,
 	}
 
+	// Period and polarity can only be changed while the channel is disabled,
+	// and the duty cycle must not exceed the new period
+	os.WriteFile(chipPath+"enable", []byte("0"), 0644)
+	os.WriteFile(chipPath+"duty_cycle", []byte("0"), 0644)
+
 	// Set period
 	periodPath := chipPath + "period"
 	periodNs := pwm.period.Nanoseconds()
@@ -109,127 +167,80 @@ func (c *Controller) initHardwarePWM(chipStr string) (*HardwarePWM, error) {
 		return nil, fmt.Errorf("failed to set PWM period: %v", err)
 	}
 
+	// Set polarity
+	if pwm.setPolarity(polarity) {
+		// The controller inverts the output, duty cycles are written as is
+		pwm.polarity = config.FanPolarityNormal
+	} else if polarity == config.FanPolarityInverted {
+		log.Println("PWM polarity not supported by the driver, inverting in software")
+	}
+
 	// Enable PWM
 	enablePath := chipPath + "enable"
 	if err := os.WriteFile(enablePath, []byte("1"), 0644); err != nil {
 		return nil, fmt.Errorf("failed to enable PWM: %v", err)
 	}
 
-	log.Printf("Hardware PWM initialized on chip %s", chipStr)
+	log.Printf("Hardware PWM initialized on pwmchip%s channel %s at %.0f Hz (%s polarity)", settings.Chip, channel, settings.Frequency, polarity)
 	return pwm, nil
 }
 
-func (c *Controller) initSoftwarePWM(chipStr, lineStr string) (*SoftwarePWM, error) {
-	// Initialize periph.io
-	if _, err := host.Init(); err != nil {
-		return nil, fmt.Errorf("failed to initialize periph.io: %v", err)
+func (c *Controller) initSoftwarePWM(chipStr, lineStr string, frequency float64, polarity config.FanPolarity) (*SoftwarePWM, error) {
+	if frequency > maxSoftwareFrequency {
+		log.Printf("Warning: software PWM can't reach %.0f Hz, using %d Hz", frequency, maxSoftwareFrequency)
+		frequency = maxSoftwareFrequency
 	}
 
-	// Convert chip and line to GPIO pin name
-	pinName := fmt.Sprintf("GPIO%s_%s", chipStr, lineStr)
-	pin := gpioreg.ByName(pinName)
-	if pin == nil {
-		// Try alternative naming
-		pinName = fmt.Sprintf("GPIO%s", lineStr)
-		pin = gpioreg.ByName(pinName)
-		if pin == nil {
-			return nil, fmt.Errorf("failed to find GPIO pin %s or %s", fmt.Sprintf("GPIO%s_%s", chipStr, lineStr), pinName)
-		}
+	pin, err := gpiocdev.Open(chipStr, lineStr, consumer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fan GPIO line: %v", err)
 	}
 
-	// Configure as output
-	if err := pin.Out(gpio.Low); err != nil {
+	// Configure as output with the fan off
+	if err := pin.Out(NearestLevel(0, polarity)); err != nil {
+		pin.Close()
 		return nil, fmt.Errorf("failed to configure GPIO pin as output: %v", err)
 	}
 
-	swPWM := &SoftwarePWM{
-		pin:    pin,
-		period: 25 * time.Millisecond, // 40Hz frequency for software PWM
-		stopCh: make(chan struct{}),
+	backend := config.GlobalConfig.Fan.SoftPWM
+	swPWM, err := newSoftwarePWM(pin, frequency, polarity, backend)
+	if err != nil {
+		pin.Close()
+		return nil, err
 	}
 
-	// Start PWM goroutine
-	go swPWM.runPWM()
-
-	log.Printf("Software PWM initialized on GPIO%s_%s", chipStr, lineStr)
+	log.Printf("Software PWM initialized on %s at %.0f Hz (%s polarity, %s backend)", pin, frequency, polarity, backend)
 	return swPWM, nil
 }
 
+// setPolarity writes the sysfs polarity, returns false if the driver
+// doesn't support it
+func (h *HardwarePWM) setPolarity(polarity config.FanPolarity) bool {
+	value := "normal"
+	if polarity == config.FanPolarityInverted {
+		value = "inversed"
+	}
+	return os.WriteFile(h.chipPath+"polarity", []byte(value), 0644) == nil
+}
+
 // SetDutyCycle for HardwarePWM
-func (h *HardwarePWM) SetDutyCycle(duty float64) error {
+func (h *HardwarePWM) SetDutyCycle(power float64) error {
 	dutyPath := h.chipPath + "duty_cycle"
-	dutyNs := int64(float64(h.period.Nanoseconds()) * duty)
+	dutyNs := int64(float64(h.period.Nanoseconds()) * HighFraction(power, h.polarity))
 	return os.WriteFile(dutyPath, []byte(strconv.FormatInt(dutyNs, 10)), 0644)
 }
 
-// Close for HardwarePWM
-func (h *HardwarePWM) Close() error {
-	// Disable PWM
-	enablePath := h.chipPath + "enable"
-	return os.WriteFile(enablePath, []byte("0"), 0644)
-}
-
-// SetDutyCycle for SoftwarePWM
-func (s *SoftwarePWM) SetDutyCycle(duty float64) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-	s.duty = duty
-	return nil
+// Disable turns the PWM channel off
+func (h *HardwarePWM) Disable() error {
+	return os.WriteFile(h.chipPath+"enable", []byte("0"), 0644)
 }
 
-// Close for SoftwarePWM
-func (s *SoftwarePWM) Close() error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	if s.running {
-		close(s.stopCh)
-		s.running = false
-	}
+// Close for HardwarePWM. The channel is left enabled so the fan keeps
+// running at the last duty cycle after the daemon exits.
+func (h *HardwarePWM) Close() error {
 	return nil
 }
 
-// runPWM runs the software PWM loop
-func (s *SoftwarePWM) runPWM() {
-	s.mutex.Lock()
-	s.running = true
-	s.mutex.Unlock()
-
-	ticker := time.NewTicker(s.period)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-s.stopCh:
-			return
-		case <-ticker.C:
-			s.mutex.RLock()
-			duty := s.duty
-			s.mutex.RUnlock()
-
-			if duty <= 0.001 {
-				// Fully off
-				s.pin.Out(gpio.Low)
-				continue
-			}
-			if duty >= 0.999 {
-				// Fully on
-				s.pin.Out(gpio.High)
-				continue
-			}
-
-			// PWM cycle
-			onTime := time.Duration(float64(s.period.Nanoseconds()) * duty)
-			offTime := s.period - onTime
-
-			s.pin.Out(gpio.High)
-			time.Sleep(onTime)
-			s.pin.Out(gpio.Low)
-			time.Sleep(offTime)
-		}
-	}
-}
-
 // Start begins the fan control loop
 func (c *Controller) Start() error {
 	c.mutex.Lock()
@@ -245,83 +256,405 @@ func (c *Controller) Start() error {
 		}
 	}
 
+	filter, err := newTempFilter(config.GlobalConfig.Fan.Smoothing, config.GlobalConfig.Fan.SmoothingWindow)
+	if err != nil {
+		return err
+	}
+	c.filter = filter
+
 	c.running = true
 	c.stopCh = make(chan struct{})
+	c.doneCh = make(chan struct{})
 
 	go c.controlLoop()
 	log.Println("Fan controller started")
 	return nil
 }
 
-// Stop stops the fan control loop
+// Stop stops the fan control loop and waits for it to exit, so an update in
+// flight can't overwrite the safe duty
 func (c *Controller) Stop() {
 	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
 	if !c.running {
+		c.mutex.Unlock()
 		return
 	}
 
 	c.running = false
 	close(c.stopCh)
+	doneCh := c.doneCh
+	c.mutex.Unlock()
 
-	if c.pwm != nil {
-		c.pwm.Close()
-	}
+	<-doneCh
+
+	// Leave the fan at the safe duty rather than stopping it
+	c.mutex.Lock()
+	c.holdSafeDuty()
+	c.mutex.Unlock()
 
 	log.Println("Fan controller stopped")
 }
 
+// FailSafe drives the fan at the configured safe duty and releases the PWM
+// so the duty holds after the process exits. Meant for recovered panics.
+func (c *Controller) FailSafe() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.holdSafeDuty()
+}
+
+// holdSafeDuty sets the safe duty and closes the PWM, caller holds the mutex
+func (c *Controller) holdSafeDuty() {
+	if c.pwm == nil {
+		return
+	}
+
+	c.applySafeDuty("exit")
+	if err := c.pwm.Close(); err != nil {
+		log.Printf("Failed to hold fan at safe duty: %v", err)
+	}
+}
+
+// applySafeDuty sets the fan to the configured safe duty, caller holds the mutex
+func (c *Controller) applySafeDuty(reason string) {
+	duty := config.GlobalConfig.Fan.SafeDuty
+
+	if err := c.pwm.SetDutyCycle(duty); err != nil {
+		log.Printf("Failed to set fan to safe duty: %v", err)
+		return
+	}
+	if duty != c.lastDuty {
+		log.Printf("Fan set to safe duty %.0f%% (%s)", duty, reason)
+	}
+	c.lastDuty = duty
+}
+
 // controlLoop is the main fan control loop
 func (c *Controller) controlLoop() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	defer close(c.doneCh)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Fan control loop panicked: %v", r)
+			c.FailSafe()
+			panic(r)
+		}
+	}()
 
-	sysInfo := sysinfo.GetInstance()
+	interval := time.Duration(config.GlobalConfig.Fan.Interval * float64(time.Second))
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
 	for {
 		select {
 		case <-c.stopCh:
 			return
 		case <-ticker.C:
-			c.updateFanSpeed(sysInfo)
+			c.updateFanSpeed()
 		}
 	}
 }
 
-// updateFanSpeed updates the fan speed based on temperature
-func (c *Controller) updateFanSpeed(sysInfo *sysinfo.SystemInfo) {
+// updateFanSpeed samples the temperature and updates the fan speed
+func (c *Controller) updateFanSpeed() {
 	now := time.Now()
+	limit := config.GlobalConfig.Fan.SensorFailures
 
-	// Update temperature cache every 60 seconds
-	var temp float64
-	if now.Sub(c.tempCache) > 60*time.Second {
-		if err := sysInfo.Update(); err != nil {
-			log.Printf("Failed to update system info: %v", err)
-			return
+	sample, err := sysinfo.ReadCPUTemperature()
+	if err != nil {
+		c.failures++
+		if c.failures <= limit {
+			log.Printf("Failed to read CPU temperature (%d/%d): %v", c.failures, limit, err)
 		}
-		temp = sysInfo.CPUTemp
-		c.tempCache = now
-		c.lastTemp = temp
-	} else {
-		temp = c.lastTemp
+		if c.failures >= limit {
+			c.mutex.Lock()
+			c.applySafeDuty("sensor failure")
+			c.mutex.Unlock()
+		}
+		return
 	}
+	if c.failures >= limit {
+		log.Println("Temperature sensor recovered, resuming automatic control")
+	}
+	c.failures = 0
+
+	temp := c.filter.add(sample)
+	c.mutex.Lock()
+	c.lastTemp = temp
+	c.mutex.Unlock()
 
-	// Calculate duty cycle based on temperature
+	// Calculate fan power based on temperature
 	duty := config.GlobalConfig.GetFanDutyCycle(temp)
+	if config.GlobalConfig.Fan.DiskAware {
+		duty = c.applyDiskPolicy(duty, temp)
+	}
+	duty = c.applyProfileCap(duty, temp, now)
+	if power, ok := c.checkOverride(); ok {
+		duty = power
+	}
+	if c.GetMode() == ModeFull {
+		duty = 100
+	}
+
+	target := duty
+	if c.IsForcedFull() {
+		// Overheating, don't wait for the ramp
+		target = 100
+		duty = 100
+	} else {
+		duty = c.applyRampRate(duty, now)
+	}
 
 	// Only update if duty cycle changed
-	if duty != c.lastDuty {
-		if err := c.pwm.SetDutyCycle(duty); err != nil {
-			log.Printf("Failed to set fan duty cycle: %v", err)
+	lastDuty := c.getLastDuty()
+	if duty == lastDuty {
+		return
+	}
+	if lastDuty <= 0 && duty > 0 && !c.kickStart() {
+		// Stopping, Stop sets the safe duty once the loop has exited
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := c.pwm.SetDutyCycle(duty); err != nil {
+		log.Printf("Failed to set fan duty cycle: %v", err)
+		return
+	}
+	// Intermediate ramp steps aren't logged
+	if duty == target {
+		log.Printf("Fan duty cycle set to %.1f%% (temp: %.1f°C)", duty, temp)
+	}
+	c.lastDuty = duty
+}
+
+// getLastDuty returns the fan power last written, -1 before the first
+func (c *Controller) getLastDuty() float64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.lastDuty
+}
+
+// applyRampRate limits how fast the fan power changes, in both directions
+func (c *Controller) applyRampRate(duty float64, now time.Time) float64 {
+	rate := config.GlobalConfig.Fan.RampRate
+	elapsed := now.Sub(c.lastRamp).Seconds()
+	c.lastRamp = now
+	lastDuty := c.getLastDuty()
+
+	// Nothing to ramp from before the first duty is set
+	if rate <= 0 || lastDuty < 0 {
+		return duty
+	}
+
+	step := rate * elapsed
+	return math.Max(lastDuty-step, math.Min(lastDuty+step, duty))
+}
+
+// kickStart runs the fan at full power for the configured time, so it
+// starts from standstill before settling at a low duty. It returns false
+// when the controller was stopped meanwhile.
+func (c *Controller) kickStart() bool {
+	kick := time.Duration(config.GlobalConfig.Fan.Kick) * time.Millisecond
+	if kick <= 0 {
+		return true
+	}
+
+	c.mutex.Lock()
+	err := c.pwm.SetDutyCycle(100)
+	c.mutex.Unlock()
+	if err != nil {
+		log.Printf("Failed to kick-start fan: %v", err)
+		return true
+	}
+
+	select {
+	case <-c.stopCh:
+		return false
+	case <-time.After(kick):
+		return true
+	}
+}
+
+// applyDiskPolicy raises the fan power for hot disks and caps it with the
+// quiet profile while every disk is in standby and the CPU is cool
+func (c *Controller) applyDiskPolicy(duty, cpuTemp float64) float64 {
+	cfg := config.GlobalConfig
+	diskPower := disk.GetInstance()
+
+	if diskTemp, ok := diskPower.GetMaxTemperature(); ok {
+		duty = math.Max(duty, cfg.GetDiskFanDutyCycle(diskTemp))
+	}
+
+	quiet := diskPower.AllStandby() && cpuTemp < cfg.Fan.QuietTemp
+	if quiet != c.quiet {
+		if quiet {
+			log.Printf("All disks in standby, quiet fan profile (max %.0f%%)", cfg.Fan.QuietMax)
 		} else {
-			log.Printf("Fan duty cycle set to %.1f%% (temp: %.1f°C)", (1.0-duty)*100, temp)
-			c.lastDuty = duty
+			log.Println("Leaving quiet fan profile")
 		}
+		c.quiet = quiet
+	}
+	if quiet {
+		duty = math.Min(duty, cfg.Fan.QuietMax)
+	}
+
+	return duty
+}
+
+// applyProfileCap limits the fan power to the maximum of the scheduled
+// profile, unless the CPU or a disk is above the override temperature
+func (c *Controller) applyProfileCap(duty, cpuTemp float64, now time.Time) float64 {
+	cfg := config.GlobalConfig
+	profile := cfg.GetFanProfile(now)
+
+	if profile.Name != c.profile {
+		log.Printf("Fan profile %s active (max %.0f%%)", profile.Name, profile.Max)
+		c.profile = profile.Name
+	}
+
+	hottest := cpuTemp
+	if diskTemp, ok := disk.GetInstance().GetMaxTemperature(); ok && diskTemp > hottest {
+		hottest = diskTemp
+	}
+	if cfg.Fan.OverrideTemp > 0 && hottest >= cfg.Fan.OverrideTemp {
+		return duty
+	}
+
+	return math.Min(duty, profile.Max)
+}
+
+// SetOverride runs the fan at a fixed power in percent for the duration,
+// after which automatic control resumes
+func (c *Controller) SetOverride(power float64, duration time.Duration) {
+	c.mutex.Lock()
+	c.override = math.Max(0, math.Min(100, power))
+	c.overrideUntil = time.Now().Add(duration)
+	c.mutex.Unlock()
+
+	log.Printf("Fan override: %.0f%% for %s", power, duration)
+}
+
+// ClearOverride returns the fan to automatic control
+func (c *Controller) ClearOverride() {
+	c.mutex.Lock()
+	active := time.Now().Before(c.overrideUntil)
+	c.overrideUntil = time.Time{}
+	c.overridden = false
+	c.mutex.Unlock()
+
+	if active {
+		log.Println("Fan override cleared")
+	}
+}
+
+// GetOverride returns the override power and remaining time, ok is false
+// when no override is active
+func (c *Controller) GetOverride() (power float64, remaining time.Duration, ok bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	remaining = time.Until(c.overrideUntil)
+	if remaining <= 0 {
+		return 0, 0, false
 	}
+	return c.override, remaining, true
+}
+
+// checkOverride returns the active override power and notes when an
+// override has expired since the last check
+func (c *Controller) checkOverride() (float64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if time.Now().Before(c.overrideUntil) {
+		c.overridden = true
+		return c.override, true
+	}
+	if c.overridden {
+		log.Println("Fan override expired, back to automatic control")
+		c.overridden = false
+	}
+	return 0, false
+}
+
+// GetOverrideUntil returns the override power and the time it ends
+func (c *Controller) GetOverrideUntil() (float64, time.Time, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !time.Now().Before(c.overrideUntil) {
+		return 0, time.Time{}, false
+	}
+	return c.override, c.overrideUntil, true
+}
+
+// FormatOverride returns the override status for the display
+func (c *Controller) FormatOverride() (string, bool) {
+	power, remaining, ok := c.GetOverride()
+	if !ok {
+		return "", false
+	}
+	if remaining < time.Minute {
+		return fmt.Sprintf("Fan %.0f%% %ds left", power, int(remaining.Seconds())), true
+	}
+	return fmt.Sprintf("Fan %.0f%% %dm left", power, int(math.Ceil(remaining.Minutes()))), true
+}
+
+// SetMode switches between automatic control, fan off and full speed.
+// A manual override is cleared.
+func (c *Controller) SetMode(mode Mode) {
+	c.ClearOverride()
+
+	c.mutex.Lock()
+	c.fullSpeed = mode == ModeFull
+	c.mutex.Unlock()
+
+	// Off and auto share the run state toggled by the "switch" button action
+	config.GlobalConfig.SetRunning(mode != ModeOff)
+	log.Printf("Fan mode set to %s", mode)
+}
+
+// GetMode returns the current fan mode
+func (c *Controller) GetMode() Mode {
+	c.mutex.RLock()
+	fullSpeed := c.fullSpeed
+	c.mutex.RUnlock()
+
+	if !config.GlobalConfig.IsRunning() {
+		return ModeOff
+	}
+	if fullSpeed {
+		return ModeFull
+	}
+	return ModeAuto
+}
+
+// SetForcedFull runs the fan at 100% regardless of mode, override and profile
+// while forced. The mode is left alone, so it applies again once released.
+func (c *Controller) SetForcedFull(forced bool) {
+	c.mutex.Lock()
+	changed := c.forcedFull != forced
+	c.forcedFull = forced
+	c.mutex.Unlock()
+
+	if changed && forced {
+		log.Println("Fan forced to 100%")
+	} else if changed {
+		log.Printf("Fan no longer forced, back to %s", c.GetMode())
+	}
+}
+
+// IsForcedFull returns whether the fan is forced to 100%
+func (c *Controller) IsForcedFull() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.forcedFull
 }
 
-// GetTemperature returns the last cached CPU temperature
+// GetTemperature returns the last smoothed CPU temperature
 func (c *Controller) GetTemperature() float64 {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
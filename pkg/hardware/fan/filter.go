@@ -0,0 +1,72 @@
+package fan
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Temperature smoothing modes
+const (
+	SmoothingNone   = "none"   // Use every sample as is
+	SmoothingEMA    = "ema"    // Exponential moving average over about window samples
+	SmoothingMedian = "median" // Median of the last window samples
+)
+
+// tempFilter smooths temperature samples so short spikes don't make the
+// fan hunt between levels
+type tempFilter struct {
+	mode    string
+	window  int
+	alpha   float64
+	samples []float64
+	average float64
+	primed  bool
+}
+
+// newTempFilter returns a filter for the smoothing mode and window size
+func newTempFilter(mode string, window int) (*tempFilter, error) {
+	if window < 1 {
+		window = 1
+	}
+
+	switch mode {
+	case SmoothingNone, SmoothingEMA, SmoothingMedian:
+	default:
+		return nil, fmt.Errorf("unknown smoothing %q (expected %s, %s or %s)", mode, SmoothingNone, SmoothingEMA, SmoothingMedian)
+	}
+
+	return &tempFilter{
+		mode:   mode,
+		window: window,
+		alpha:  2.0 / float64(window+1), // Same center of mass as a window-sample average
+	}, nil
+}
+
+// add records a sample and returns the smoothed temperature
+func (f *tempFilter) add(sample float64) float64 {
+	switch f.mode {
+	case SmoothingEMA:
+		if !f.primed {
+			f.average = sample
+			f.primed = true
+		} else {
+			f.average += f.alpha * (sample - f.average)
+		}
+		return f.average
+
+	case SmoothingMedian:
+		f.samples = append(f.samples, sample)
+		if len(f.samples) > f.window {
+			f.samples = f.samples[1:]
+		}
+		sorted := append([]float64(nil), f.samples...)
+		sort.Float64s(sorted)
+		middle := len(sorted) / 2
+		if len(sorted)%2 == 0 {
+			return (sorted[middle-1] + sorted[middle]) / 2
+		}
+		return sorted[middle]
+	}
+
+	return sample
+}
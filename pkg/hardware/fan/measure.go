@@ -0,0 +1,132 @@
+package fan
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"periph.io/x/conn/v3/gpio"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+// MeasuringPin is a fake output pin that records the time spent high and
+// the rising edges, used to check the accuracy of the software PWM
+type MeasuringPin struct {
+	gpio.PinIO // Everything but Out fails like gpio.INVALID
+
+	mutex   sync.Mutex
+	level   gpio.Level
+	since   time.Time
+	high    time.Duration
+	total   time.Duration
+	rising  []time.Time
+	started bool
+}
+
+// PWMStats is the measured output of a PWM
+type PWMStats struct {
+	Duty       float64       // Achieved high time in percent
+	Periods    int           // Number of rising edges
+	MeanJitter time.Duration // Mean deviation of the rising edge interval from the period
+	MaxJitter  time.Duration // Largest deviation of the rising edge interval
+}
+
+// NewMeasuringPin returns a fake pin that starts low
+func NewMeasuringPin() *MeasuringPin {
+	return &MeasuringPin{PinIO: gpio.INVALID}
+}
+
+// Out records a level change
+func (p *MeasuringPin) Out(level gpio.Level) error {
+	now := time.Now()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.started {
+		p.account(now)
+	}
+	if level == gpio.High && (!p.started || p.level == gpio.Low) {
+		p.rising = append(p.rising, now)
+	}
+	p.level = level
+	p.started = true
+	return nil
+}
+
+// account adds the time since the last change, caller holds the mutex
+func (p *MeasuringPin) account(now time.Time) {
+	elapsed := now.Sub(p.since)
+	if p.level == gpio.High {
+		p.high += elapsed
+	}
+	p.total += elapsed
+	p.since = now
+}
+
+// reset drops the measurements taken so far
+func (p *MeasuringPin) reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.high = 0
+	p.total = 0
+	p.rising = nil
+	p.since = time.Now()
+}
+
+// Stats returns the output measured so far for a PWM with the given period
+func (p *MeasuringPin) Stats(period time.Duration) PWMStats {
+	now := time.Now()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.started {
+		p.account(now)
+	}
+
+	stats := PWMStats{Periods: len(p.rising)}
+	if p.total > 0 {
+		stats.Duty = float64(p.high) / float64(p.total) * 100
+	}
+
+	var sum time.Duration
+	for i := 1; i < len(p.rising); i++ {
+		deviation := p.rising[i].Sub(p.rising[i-1]) - period
+		if deviation < 0 {
+			deviation = -deviation
+		}
+		sum += deviation
+		stats.MaxJitter = max(stats.MaxJitter, deviation)
+	}
+	if len(p.rising) > 1 {
+		stats.MeanJitter = sum / time.Duration(len(p.rising)-1)
+	}
+
+	return stats
+}
+
+// MeasureSoftwarePWM runs a software PWM backend at the given fan power
+// against a MeasuringPin for the duration and returns what it achieved.
+// Normal polarity is used so the measured duty is the fan power.
+func MeasureSoftwarePWM(backend string, frequency, power float64, duration time.Duration) (PWMStats, error) {
+	frequency = math.Min(frequency, maxSoftwareFrequency)
+
+	pin := NewMeasuringPin()
+	pwm, err := newSoftwarePWM(pin, frequency, config.FanPolarityNormal, backend)
+	if err != nil {
+		return PWMStats{}, err
+	}
+	pwm.SetDutyCycle(power)
+
+	// Let the first cycle start before measuring
+	time.Sleep(pwm.period)
+	pin.reset()
+	time.Sleep(duration)
+
+	stats := pin.Stats(pwm.period)
+	pwm.Close()
+	return stats, nil
+}
@@ -0,0 +1,64 @@
+package fan
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeasureSoftwarePWM(t *testing.T) {
+	if testing.Short() {
+		t.Skip("measures real time")
+	}
+
+	tests := []struct {
+		backend string
+		power   float64
+		minDuty float64
+		maxDuty float64
+	}{
+		{SoftPWMTicker, 0, 0, 0},
+		{SoftPWMTicker, 50, 30, 70},
+		{SoftPWMTicker, 100, 100, 100},
+		{SoftPWMPrecise, 0, 0, 0},
+		{SoftPWMPrecise, 50, 30, 70},
+		{SoftPWMPrecise, 100, 100, 100},
+	}
+
+	for _, tt := range tests {
+		stats, err := MeasureSoftwarePWM(tt.backend, 100, tt.power, 300*time.Millisecond)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.backend, err)
+		}
+		// Shared CI machines jitter a lot, only catch a broken backend
+		if stats.Duty < tt.minDuty || stats.Duty > tt.maxDuty {
+			t.Errorf("%s at %.0f%%: measured duty %.1f%%, want %.0f-%.0f%%", tt.backend, tt.power, stats.Duty, tt.minDuty, tt.maxDuty)
+		}
+		if tt.power == 50 && stats.Periods < 10 {
+			t.Errorf("%s at 50%%: %d periods in 300ms at 100 Hz", tt.backend, stats.Periods)
+		}
+	}
+}
+
+func TestMeasureSoftwarePWMUnknownBackend(t *testing.T) {
+	if _, err := MeasureSoftwarePWM("busy", 100, 50, time.Millisecond); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+func BenchmarkSoftwarePWM(b *testing.B) {
+	for _, backend := range []string{SoftPWMTicker, SoftPWMPrecise} {
+		b.Run(backend, func(b *testing.B) {
+			var duty, jitter float64
+			for i := 0; i < b.N; i++ {
+				stats, err := MeasureSoftwarePWM(backend, 1000, 40, 100*time.Millisecond)
+				if err != nil {
+					b.Fatal(err)
+				}
+				duty += stats.Duty
+				jitter += float64(stats.MeanJitter.Microseconds())
+			}
+			b.ReportMetric(duty/float64(b.N), "duty%")
+			b.ReportMetric(jitter/float64(b.N), "jitter-us")
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package fan
+
+import (
+	"periph.io/x/conn/v3/gpio"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+// Fractions this close to the ends of the period are driven as a constant
+// level instead of a PWM cycle
+const steadyMargin = 0.001
+
+// HighFraction returns the fraction of the PWM period the pin is high for
+// the given fan power in percent
+func HighFraction(power float64, polarity config.FanPolarity) float64 {
+	fraction := power / 100.0
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+
+	if polarity == config.FanPolarityInverted {
+		return 1.0 - fraction
+	}
+	return fraction
+}
+
+// SteadyLevel returns the constant pin level for the fan power when no PWM
+// cycle is needed, i.e. the fan is fully off or at full power
+func SteadyLevel(power float64, polarity config.FanPolarity) (gpio.Level, bool) {
+	fraction := HighFraction(power, polarity)
+	switch {
+	case fraction <= steadyMargin:
+		return gpio.Low, true
+	case fraction >= 1.0-steadyMargin:
+		return gpio.High, true
+	}
+	return gpio.Low, false
+}
+
+// NearestLevel returns the constant pin level closest to the fan power, used
+// to hold the fan once the PWM loop has stopped
+func NearestLevel(power float64, polarity config.FanPolarity) gpio.Level {
+	if HighFraction(power, polarity) < 0.5 {
+		return gpio.Low
+	}
+	return gpio.High
+}
@@ -0,0 +1,138 @@
+package fan
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"periph.io/x/conn/v3/gpio"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hostfs"
+)
+
+func TestPolarityLevels(t *testing.T) {
+	tests := []struct {
+		polarity config.FanPolarity
+		power    float64
+		high     float64    // Expected HighFraction
+		steady   gpio.Level // Expected SteadyLevel when isSteady
+		isSteady bool
+		nearest  gpio.Level
+	}{
+		{config.FanPolarityNormal, 0, 0, gpio.Low, true, gpio.Low},
+		{config.FanPolarityNormal, 30, 0.3, gpio.Low, false, gpio.Low},
+		{config.FanPolarityNormal, 70, 0.7, gpio.Low, false, gpio.High},
+		{config.FanPolarityNormal, 100, 1, gpio.High, true, gpio.High},
+		{config.FanPolarityNormal, -10, 0, gpio.Low, true, gpio.Low},
+		{config.FanPolarityNormal, 150, 1, gpio.High, true, gpio.High},
+		{config.FanPolarityInverted, 0, 1, gpio.High, true, gpio.High},
+		{config.FanPolarityInverted, 30, 0.7, gpio.Low, false, gpio.High},
+		{config.FanPolarityInverted, 70, 0.3, gpio.Low, false, gpio.Low},
+		{config.FanPolarityInverted, 100, 0, gpio.Low, true, gpio.Low},
+	}
+
+	for _, tt := range tests {
+		if got := HighFraction(tt.power, tt.polarity); math.Abs(got-tt.high) > 1e-9 {
+			t.Errorf("HighFraction(%v, %s) = %v, want %v", tt.power, tt.polarity, got, tt.high)
+		}
+
+		level, steady := SteadyLevel(tt.power, tt.polarity)
+		if steady != tt.isSteady || (steady && level != tt.steady) {
+			t.Errorf("SteadyLevel(%v, %s) = %v, %t, want %v, %t", tt.power, tt.polarity, level, steady, tt.steady, tt.isSteady)
+		}
+
+		if got := NearestLevel(tt.power, tt.polarity); got != tt.nearest {
+			t.Errorf("NearestLevel(%v, %s) = %v, want %v", tt.power, tt.polarity, got, tt.nearest)
+		}
+	}
+}
+
+// fakePWMChip creates pwmchip0 with one exported channel below a new root.
+// Without polarity support the polarity attribute is a directory, so
+// writing it fails like on drivers that reject it.
+func fakePWMChip(t *testing.T, polaritySupported bool) string {
+	t.Helper()
+	root := t.TempDir()
+	channel := filepath.Join(root, "sys/class/pwm/pwmchip0/pwm0")
+	if err := os.MkdirAll(channel, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sys/class/pwm/pwmchip0/npwm"), []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !polaritySupported {
+		if err := os.Mkdir(filepath.Join(channel, "polarity"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hostfs.SetRoot(root)
+	t.Cleanup(func() { hostfs.SetRoot("/") })
+	return channel
+}
+
+func TestHardwarePWMPolarity(t *testing.T) {
+	tests := []struct {
+		name      string
+		polarity  config.FanPolarity
+		supported bool
+		sysfs     string // Expected polarity attribute, "" when unsupported
+		dutyNs    string // Expected duty_cycle at 25% power
+	}{
+		{"inverted in hardware", config.FanPolarityInverted, true, "inversed", "10000"},
+		{"inverted in software", config.FanPolarityInverted, false, "", "30000"},
+		{"normal", config.FanPolarityNormal, true, "normal", "10000"},
+		{"normal without polarity support", config.FanPolarityNormal, false, "", "10000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := fakePWMChip(t, tt.supported)
+
+			pwm, err := OpenHardwarePWM(config.PWMSettings{Chip: "0", Channel: 0, Frequency: 25000}, tt.polarity)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := pwm.SetDutyCycle(25); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.sysfs != "" {
+				if got := readAttr(t, channel, "polarity"); got != tt.sysfs {
+					t.Errorf("polarity = %q, want %q", got, tt.sysfs)
+				}
+			}
+			if got := readAttr(t, channel, "period"); got != "40000" {
+				t.Errorf("period = %q, want 40000", got)
+			}
+			if got := readAttr(t, channel, "duty_cycle"); got != tt.dutyNs {
+				t.Errorf("duty_cycle = %q, want %q", got, tt.dutyNs)
+			}
+			if got := readAttr(t, channel, "enable"); got != "1" {
+				t.Errorf("enable = %q, want 1", got)
+			}
+		})
+	}
+}
+
+func TestOpenHardwarePWMChannelRange(t *testing.T) {
+	fakePWMChip(t, true)
+
+	_, err := OpenHardwarePWM(config.PWMSettings{Chip: "0", Channel: 1, Frequency: 25000}, config.FanPolarityNormal)
+	if err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("err = %v, want channel out of range", err)
+	}
+}
+
+// readAttr returns a sysfs attribute written by the PWM
+func readAttr(t *testing.T, channel, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(channel, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,183 @@
+package fan
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"periph.io/x/conn/v3/gpio"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+)
+
+// Software PWM backends
+const (
+	SoftPWMPrecise = "precise" // Locked OS thread, edges reached by sleeping then spinning
+	SoftPWMTicker  = "ticker"  // Ticker and time.Sleep, cheapest but jitters under load
+)
+
+// Highest frequency the software PWM backends can follow
+const maxSoftwareFrequency = 1000
+
+// The precise backend sleeps until this long before an edge and spins for
+// the rest, which covers the usual timer slack of the kernel
+const spinMargin = 500 * time.Microsecond
+
+// SoftwarePWM represents software PWM control using GPIO
+type SoftwarePWM struct {
+	pin      gpio.PinOut
+	period   time.Duration
+	polarity config.FanPolarity
+	duty     float64
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	running  bool
+	mutex    sync.RWMutex
+}
+
+// newSoftwarePWM starts a software PWM on an output pin
+func newSoftwarePWM(pin gpio.PinOut, frequency float64, polarity config.FanPolarity, backend string) (*SoftwarePWM, error) {
+	s := &SoftwarePWM{
+		pin:      pin,
+		period:   time.Duration(float64(time.Second) / frequency),
+		polarity: polarity,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+		running:  true,
+	}
+
+	// Start PWM goroutine
+	switch backend {
+	case SoftPWMPrecise:
+		go s.runPrecisePWM()
+	case SoftPWMTicker:
+		go s.runPWM()
+	default:
+		return nil, fmt.Errorf("unknown software PWM backend %q (expected %s or %s)", backend, SoftPWMPrecise, SoftPWMTicker)
+	}
+
+	return s, nil
+}
+
+// SetDutyCycle for SoftwarePWM
+func (s *SoftwarePWM) SetDutyCycle(power float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.duty = power
+	return nil
+}
+
+// Close for SoftwarePWM. The PWM loop is stopped and the pin is held, which
+// persists after the daemon exits. A held pin can only be fully on or off, so
+// any power above zero keeps the fan on rather than risking it stopping.
+func (s *SoftwarePWM) Close() error {
+	s.mutex.Lock()
+	if !s.running {
+		s.mutex.Unlock()
+		return nil
+	}
+	close(s.stopCh)
+	s.running = false
+	duty := s.duty
+	s.mutex.Unlock()
+
+	// Wait for the current cycle to finish before taking over the pin
+	<-s.doneCh
+
+	if duty > 0 {
+		duty = 100
+	}
+	return s.pin.Out(NearestLevel(duty, s.polarity))
+}
+
+// getDuty returns the current fan power
+func (s *SoftwarePWM) getDuty() float64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.duty
+}
+
+// runPWM runs the ticker based software PWM loop
+func (s *SoftwarePWM) runPWM() {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(s.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			duty := s.getDuty()
+
+			// Fan off or at full power
+			if level, ok := SteadyLevel(duty, s.polarity); ok {
+				s.pin.Out(level)
+				continue
+			}
+
+			// PWM cycle
+			onTime := time.Duration(float64(s.period.Nanoseconds()) * HighFraction(duty, s.polarity))
+			offTime := s.period - onTime
+
+			s.pin.Out(gpio.High)
+			time.Sleep(onTime)
+			s.pin.Out(gpio.Low)
+			time.Sleep(offTime)
+		}
+	}
+}
+
+// runPrecisePWM runs the software PWM on its own OS thread. Edges are
+// scheduled on absolute times so latency doesn't accumulate, and each edge
+// is approached with a sleep followed by a short spin.
+func (s *SoftwarePWM) runPrecisePWM() {
+	defer close(s.doneCh)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	start := time.Now()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		// After a stall, restart the schedule instead of replaying periods
+		if now := time.Now(); now.Sub(start) > s.period {
+			start = now
+		}
+		next := start.Add(s.period)
+		duty := s.getDuty()
+
+		// Fan off or at full power, nothing to time precisely
+		if level, ok := SteadyLevel(duty, s.polarity); ok {
+			s.pin.Out(level)
+			time.Sleep(time.Until(next))
+			start = next
+			continue
+		}
+
+		onTime := time.Duration(float64(s.period.Nanoseconds()) * HighFraction(duty, s.polarity))
+
+		waitUntil(start)
+		s.pin.Out(gpio.High)
+		waitUntil(start.Add(onTime))
+		s.pin.Out(gpio.Low)
+		start = next
+	}
+}
+
+// waitUntil blocks until t, sleeping for most of the wait and spinning for
+// the last spinMargin
+func waitUntil(t time.Time) {
+	if d := time.Until(t) - spinMargin; d > 0 {
+		time.Sleep(d)
+	}
+	for time.Now().Before(t) {
+	}
+}
@@ -0,0 +1,102 @@
+// Package gpiocdev talks to GPIO controllers through the kernel character
+// device API (/dev/gpiochipN).
+package gpiocdev
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// GPIO_GET_CHIPINFO_IOCTL from linux/gpio.h
+const getChipInfoIoctl = 0x8044b401
+
+// chipInfo mirrors struct gpiochip_info
+type chipInfo struct {
+	name  [32]byte
+	label [32]byte
+	lines uint32
+}
+
+// ChipInfo describes a GPIO controller
+type ChipInfo struct {
+	Path  string // e.g. /dev/gpiochip4
+	Name  string // e.g. gpiochip4
+	Label string // Driver label, e.g. pinctrl-rp1
+	Lines int
+}
+
+// Number returns N of /dev/gpiochipN
+func (c ChipInfo) Number() string {
+	return strings.TrimPrefix(c.Name, "gpiochip")
+}
+
+// GetChipInfo queries the controller behind a /dev/gpiochipN device
+func GetChipInfo(path string) (ChipInfo, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return ChipInfo{}, err
+	}
+	defer file.Close()
+
+	var info chipInfo
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), getChipInfoIoctl, uintptr(unsafe.Pointer(&info))); errno != 0 {
+		return ChipInfo{}, fmt.Errorf("%s: chip info: %v", path, errno)
+	}
+
+	return ChipInfo{
+		Path:  path,
+		Name:  cString(info.name[:]),
+		Label: cString(info.label[:]),
+		Lines: int(info.lines),
+	}, nil
+}
+
+// Chips returns every GPIO controller in /dev, sorted by number
+func Chips() []ChipInfo {
+	paths, _ := filepath.Glob("/dev/gpiochip*")
+	sort.Slice(paths, func(i, j int) bool {
+		return chipNumber(paths[i]) < chipNumber(paths[j])
+	})
+
+	var chips []ChipInfo
+	for _, path := range paths {
+		if info, err := GetChipInfo(path); err == nil {
+			chips = append(chips, info)
+		}
+	}
+	return chips
+}
+
+// FindChipByLabel returns the controller with the given label
+func FindChipByLabel(label string) (ChipInfo, error) {
+	for _, chip := range Chips() {
+		if chip.Label == label {
+			return chip, nil
+		}
+	}
+	return ChipInfo{}, fmt.Errorf("no GPIO chip labelled %q", label)
+}
+
+func chipNumber(path string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "gpiochip"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// cString converts a NUL terminated buffer
+func cString(buf []byte) string {
+	for i, b := range buf {
+		if b == 0 {
+			return string(buf[:i])
+		}
+	}
+	return string(buf)
+}
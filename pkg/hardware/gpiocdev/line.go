@@ -0,0 +1,469 @@
+package gpiocdev
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
+)
+
+// GPIO v2 ioctls from linux/gpio.h (kernel 5.10 and later)
+const (
+	getLineInfoIoctl  = 0xc100b405 // GPIO_V2_GET_LINEINFO_IOCTL
+	getLineIoctl      = 0xc250b407 // GPIO_V2_GET_LINE_IOCTL
+	setLineConfIoctl  = 0xc110b40d // GPIO_V2_LINE_SET_CONFIG_IOCTL
+	getLineValsIoctl  = 0xc010b40e // GPIO_V2_LINE_GET_VALUES_IOCTL
+	setLineValsIoctl  = 0xc010b40f // GPIO_V2_LINE_SET_VALUES_IOCTL
+	attrOutputValues  = 2          // GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES
+	lineEventSize     = 48         // sizeof(struct gpio_v2_line_event)
+	maxPendingEvents  = 16
+	lineConsumerBytes = 32
+)
+
+// GPIO_V2_LINE_FLAG_* from linux/gpio.h
+const (
+	flagUsed         = 1 << 0
+	flagInput        = 1 << 2
+	flagOutput       = 1 << 3
+	flagEdgeRising   = 1 << 4
+	flagEdgeFalling  = 1 << 5
+	flagBiasPullUp   = 1 << 8
+	flagBiasPullDown = 1 << 9
+	flagBiasDisabled = 1 << 10
+
+	// Flags that Restore puts back
+	restoredFlags = flagInput | flagOutput | flagBiasPullUp | flagBiasPullDown | flagBiasDisabled
+)
+
+// lineAttribute mirrors struct gpio_v2_line_attribute
+type lineAttribute struct {
+	id      uint32
+	padding uint32
+	value   uint64
+}
+
+// lineInfo mirrors struct gpio_v2_line_info
+type lineInfo struct {
+	name     [32]byte
+	consumer [32]byte
+	offset   uint32
+	numAttrs uint32
+	flags    uint64
+	attrs    [10]lineAttribute
+	padding  [4]uint32
+}
+
+// lineConfigAttribute mirrors struct gpio_v2_line_config_attribute
+type lineConfigAttribute struct {
+	attr lineAttribute
+	mask uint64
+}
+
+// lineConfig mirrors struct gpio_v2_line_config
+type lineConfig struct {
+	flags    uint64
+	numAttrs uint32
+	padding  [5]uint32
+	attrs    [10]lineConfigAttribute
+}
+
+// lineRequest mirrors struct gpio_v2_line_request
+type lineRequest struct {
+	offsets         [64]uint32
+	consumer        [lineConsumerBytes]byte
+	config          lineConfig
+	numLines        uint32
+	eventBufferSize uint32
+	padding         [5]uint32
+	fd              int32
+}
+
+// lineValues mirrors struct gpio_v2_line_values
+type lineValues struct {
+	bits uint64
+	mask uint64
+}
+
+// The ioctl numbers encode these sizes, fail the build if the layout drifts
+var (
+	_ [unsafe.Sizeof(lineInfo{}) - 256]byte
+	_ [256 - unsafe.Sizeof(lineInfo{})]byte
+	_ [unsafe.Sizeof(lineRequest{}) - 592]byte
+	_ [592 - unsafe.Sizeof(lineRequest{})]byte
+)
+
+// LineInfo describes a line of a GPIO controller
+type LineInfo struct {
+	Offset   int
+	Name     string // e.g. GPIO17, empty if the driver doesn't name its lines
+	Consumer string // Label of whoever holds the line, e.g. rockpi-penta-fan
+	Used     bool
+	Output   bool
+	flags    uint64
+}
+
+// GetLines returns every line of the controller behind a /dev/gpiochipN device
+func GetLines(chip ChipInfo) ([]LineInfo, error) {
+	file, err := os.OpenFile(chip.Path, os.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := make([]LineInfo, 0, chip.Lines)
+	for offset := 0; offset < chip.Lines; offset++ {
+		info, err := getLineInfo(file, offset)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d info: %v", chip.Path, offset, err)
+		}
+		lines = append(lines, info)
+	}
+	return lines, nil
+}
+
+func getLineInfo(chip *os.File, offset int) (LineInfo, error) {
+	info := lineInfo{offset: uint32(offset)}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, chip.Fd(), getLineInfoIoctl, uintptr(unsafe.Pointer(&info))); errno != 0 {
+		return LineInfo{}, errno
+	}
+	return LineInfo{
+		Offset:   offset,
+		Name:     cString(info.name[:]),
+		Consumer: cString(info.consumer[:]),
+		Used:     info.flags&flagUsed != 0,
+		Output:   info.flags&flagOutput != 0,
+		flags:    info.flags,
+	}, nil
+}
+
+// ResolveChip finds a controller by number (4), name (gpiochip4), device
+// path (/dev/gpiochip4) or label (pinctrl-rp1)
+func ResolveChip(chip string) (ChipInfo, error) {
+	switch {
+	case chip == "":
+		return ChipInfo{}, fmt.Errorf("no GPIO chip given")
+	case strings.HasPrefix(chip, "/"):
+		return GetChipInfo(chip)
+	case strings.HasPrefix(chip, "gpiochip"):
+		return GetChipInfo("/dev/" + chip)
+	}
+	if _, err := strconv.Atoi(chip); err == nil {
+		return GetChipInfo("/dev/gpiochip" + chip)
+	}
+	return FindChipByLabel(chip)
+}
+
+// FindLine looks up a line by chip and offset or by line name. A numeric
+// line is an offset on the chip; anything else is a line name, searched on
+// the chip or, if chip is empty, on every controller.
+func FindLine(chip, line string) (ChipInfo, int, error) {
+	var chips []ChipInfo
+	if chip != "" {
+		info, err := ResolveChip(chip)
+		if err != nil {
+			return ChipInfo{}, 0, err
+		}
+		chips = []ChipInfo{info}
+	}
+
+	if offset, err := strconv.Atoi(line); err == nil {
+		if len(chips) == 0 {
+			return ChipInfo{}, 0, fmt.Errorf("GPIO line offset %d needs a chip", offset)
+		}
+		if offset < 0 || offset >= chips[0].Lines {
+			return ChipInfo{}, 0, fmt.Errorf("GPIO line %d out of range, %s has %d line(s)", offset, chips[0].Name, chips[0].Lines)
+		}
+		return chips[0], offset, nil
+	}
+
+	if len(chips) == 0 {
+		chips = Chips()
+	}
+	for _, info := range chips {
+		lines, err := GetLines(info)
+		if err != nil {
+			continue
+		}
+		for _, l := range lines {
+			if l.Name == line {
+				return info, l.Offset, nil
+			}
+		}
+	}
+	if chip != "" {
+		return ChipInfo{}, 0, fmt.Errorf("no GPIO line named %q on %s", line, chips[0].Name)
+	}
+	return ChipInfo{}, 0, fmt.Errorf("no GPIO line named %q", line)
+}
+
+// Line is a GPIO line requested through the character device. It holds the
+// line until closed, so other processes see it as used by its consumer
+// label, and implements gpio.PinIO.
+type Line struct {
+	chip     ChipInfo
+	offset   int
+	name     string
+	consumer string
+	file     *os.File
+	flags    uint64
+	pull     gpio.Pull
+	mutex    sync.Mutex
+
+	// Direction, bias and output level when requested, see Restore
+	initial     uint64
+	initialHigh bool
+}
+
+// Open looks up a line with FindLine and requests it for consumer
+func Open(chip, line, consumer string) (*Line, error) {
+	info, offset, err := FindLine(chip, line)
+	if err != nil {
+		return nil, err
+	}
+	return Request(info, offset, consumer)
+}
+
+// Request claims a line of the controller, leaving its direction and value
+// as they are until In or Out is called
+func Request(chip ChipInfo, offset int, consumer string) (*Line, error) {
+	file, err := os.OpenFile(chip.Path, os.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	l := &Line{chip: chip, offset: offset, consumer: consumer}
+	if info, err := getLineInfo(file, offset); err == nil {
+		l.name = info.Name
+		l.initial = info.flags & restoredFlags
+	}
+
+	req := lineRequest{numLines: 1, eventBufferSize: maxPendingEvents}
+	req.offsets[0] = uint32(offset)
+	copy(req.consumer[:lineConsumerBytes-1], consumer)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), getLineIoctl, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		if errno == syscall.EBUSY {
+			holder := "another process or driver"
+			if info, err := getLineInfo(file, offset); err == nil && info.Consumer != "" {
+				holder = strconv.Quote(info.Consumer)
+			}
+			return nil, fmt.Errorf("%s is already in use by %s", l, holder)
+		}
+		return nil, fmt.Errorf("failed to request %s: %v", l, errno)
+	}
+
+	// Non-blocking so edge waits go through the runtime poller and can time out
+	syscall.SetNonblock(int(req.fd), true)
+	l.file = os.NewFile(uintptr(req.fd), fmt.Sprintf("%s-line%d", chip.Name, offset))
+	if l.initial&flagOutput != 0 {
+		l.initialHigh = l.readLocked() == gpio.High
+	}
+	return l, nil
+}
+
+// ioctl runs a line request ioctl without putting the file in blocking mode
+func (l *Line) ioctl(request uintptr, arg unsafe.Pointer) error {
+	conn, err := l.file.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// reconfigure applies new line flags, with the output value if it's an
+// output; the caller holds the mutex
+func (l *Line) reconfigure(flags uint64, high bool) error {
+	config := lineConfig{flags: flags}
+	if flags&flagOutput != 0 {
+		config.numAttrs = 1
+		config.attrs[0].attr.id = attrOutputValues
+		config.attrs[0].mask = 1
+		if high {
+			config.attrs[0].attr.value = 1
+		}
+	}
+	if err := l.ioctl(setLineConfIoctl, unsafe.Pointer(&config)); err != nil {
+		return fmt.Errorf("failed to configure %s: %v", l, err)
+	}
+	l.flags = flags
+	return nil
+}
+
+// String returns e.g. gpiochip4/17 (GPIO17)
+func (l *Line) String() string {
+	if l.name != "" {
+		return fmt.Sprintf("%s/%d (%s)", l.chip.Name, l.offset, l.name)
+	}
+	return fmt.Sprintf("%s/%d", l.chip.Name, l.offset)
+}
+
+// Name returns the line name, or the chip and offset if it has none
+func (l *Line) Name() string {
+	if l.name != "" {
+		return l.name
+	}
+	return fmt.Sprintf("%s/%d", l.chip.Name, l.offset)
+}
+
+// Number returns the offset of the line on its controller
+func (l *Line) Number() int {
+	return l.offset
+}
+
+// Function returns the current direction
+func (l *Line) Function() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	switch {
+	case l.flags&flagOutput != 0:
+		return "Out/" + l.readLocked().String()
+	case l.flags&flagInput != 0:
+		return "In/" + l.readLocked().String()
+	}
+	return ""
+}
+
+// Halt interrupts a pending WaitForEdge
+func (l *Line) Halt() error {
+	return l.file.SetReadDeadline(time.Now())
+}
+
+// Close releases the line. Most drivers keep an output at its last level.
+func (l *Line) Close() error {
+	return l.file.Close()
+}
+
+// Restore puts the line back into the direction and bias it had when it
+// was requested, an output at its level then. Lines that were never
+// reconfigured are left alone.
+func (l *Line) Restore() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.flags == 0 || l.initial&(flagInput|flagOutput) == 0 {
+		return nil
+	}
+	if err := l.reconfigure(l.initial, l.initialHigh); err != nil {
+		return err
+	}
+	l.pull = gpio.PullNoChange
+	return nil
+}
+
+// In configures the line as an input with optional bias and edge detection
+func (l *Line) In(pull gpio.Pull, edge gpio.Edge) error {
+	flags := uint64(flagInput)
+	switch pull {
+	case gpio.PullUp:
+		flags |= flagBiasPullUp
+	case gpio.PullDown:
+		flags |= flagBiasPullDown
+	case gpio.Float:
+		flags |= flagBiasDisabled
+	}
+	switch edge {
+	case gpio.RisingEdge:
+		flags |= flagEdgeRising
+	case gpio.FallingEdge:
+		flags |= flagEdgeFalling
+	case gpio.BothEdges:
+		flags |= flagEdgeRising | flagEdgeFalling
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err := l.reconfigure(flags, false); err != nil {
+		return err
+	}
+	l.pull = pull
+	return nil
+}
+
+// Read returns the current level of the line
+func (l *Line) Read() gpio.Level {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.readLocked()
+}
+
+func (l *Line) readLocked() gpio.Level {
+	values := lineValues{mask: 1}
+	if err := l.ioctl(getLineValsIoctl, unsafe.Pointer(&values)); err != nil {
+		return gpio.Low
+	}
+	return values.bits&1 != 0
+}
+
+// WaitForEdge waits for an edge enabled with In. A negative timeout waits
+// forever. Queued edges are consumed together.
+func (l *Line) WaitForEdge(timeout time.Duration) bool {
+	var deadline time.Time
+	if timeout >= 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := l.file.SetReadDeadline(deadline); err != nil {
+		return false
+	}
+
+	var events [maxPendingEvents * lineEventSize]byte
+	n, err := l.file.Read(events[:])
+	if err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+		return false
+	}
+	return n >= lineEventSize
+}
+
+// Pull returns the bias set with In
+func (l *Line) Pull() gpio.Pull {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.pull
+}
+
+// DefaultPull is unknown through the character device
+func (l *Line) DefaultPull() gpio.Pull {
+	return gpio.PullNoChange
+}
+
+// Out drives the line, switching it to an output first if needed
+func (l *Line) Out(level gpio.Level) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.flags&flagOutput == 0 {
+		return l.reconfigure(flagOutput, bool(level))
+	}
+
+	values := lineValues{mask: 1}
+	if level {
+		values.bits = 1
+	}
+	if err := l.ioctl(setLineValsIoctl, unsafe.Pointer(&values)); err != nil {
+		return fmt.Errorf("failed to set %s: %v", l, err)
+	}
+	return nil
+}
+
+// PWM isn't available on character device lines
+func (l *Line) PWM(gpio.Duty, physic.Frequency) error {
+	return fmt.Errorf("%s: PWM not supported, use a software PWM", l)
+}
+
+var _ gpio.PinIO = &Line{}
@@ -4,6 +4,7 @@ import (
 	"embed"
 	"fmt"
 	"image"
+	"image/draw"
 	"log"
 	"os"
 	"sync"
@@ -18,6 +19,7 @@ import (
 	"periph.io/x/host/v3"
 
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/fan"
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/sysinfo"
 )
 
@@ -25,16 +27,34 @@ import (
 var fontFS embed.FS
 
 type Controller struct {
-	device      *ssd1306.Dev
-	width       int
-	height      int
-	ctx         *gg.Context
-	fonts       map[int]font.Face
-	running     bool
-	autoSliding bool
-	stopCh      chan struct{}
-	mutex       sync.RWMutex
-	currentPage int
+	device       *ssd1306.Dev
+	width        int
+	height       int
+	ctx          *gg.Context
+	fonts        map[int]font.Face
+	running      bool
+	autoSliding  bool
+	stopCh       chan struct{}
+	mutex        sync.RWMutex
+	currentPage  int
+	blanked      bool
+	forcedBlank  bool
+	overlay      bool
+	alert        bool // A warning from ShowAlert keeps the panel on
+	alertWasOff  bool // The panel was turned off before the alert
+	shiftIndex   int
+	lastActivity time.Time
+}
+
+// shiftOffsets are the pixel offsets cycled through to spread wear across the panel.
+// Text is laid out from the top-left corner, so offsets only move right and up.
+var shiftOffsets = []image.Point{
+	{X: 0, Y: 0},
+	{X: 1, Y: 0},
+	{X: 2, Y: 0},
+	{X: 2, Y: -1},
+	{X: 1, Y: -1},
+	{X: 0, Y: -1},
 }
 
 type Page struct {
@@ -116,6 +136,11 @@ func (c *Controller) Initialize() error {
 
 	c.device = device
 
+	// Apply configured contrast
+	if err := c.setContrast(config.GlobalConfig.OLED.Contrast); err != nil {
+		log.Printf("Warning: failed to set OLED contrast: %v", err)
+	}
+
 	// Initialize drawing context
 	c.ctx = gg.NewContext(c.width, c.height)
 
@@ -187,6 +212,12 @@ func (c *Controller) Start() error {
 
 	c.running = true
 	c.stopCh = make(chan struct{})
+	c.blanked = false
+	c.forcedBlank = false
+	c.alert = false
+	c.alertWasOff = false
+	c.overlay = false
+	c.lastActivity = time.Now()
 
 	// Show welcome message
 	c.showWelcome()
@@ -196,6 +227,9 @@ func (c *Controller) Start() error {
 		go c.autoSliderLoop()
 	}
 
+	// Start burn-in protection (pixel shift, idle timeout, night schedule)
+	go c.screenSaverLoop()
+
 	log.Println("OLED controller started")
 	return nil
 }
@@ -211,6 +245,7 @@ func (c *Controller) Stop() {
 
 	c.running = false
 	close(c.stopCh)
+	c.blanked = false
 
 	// Show goodbye message
 	c.showGoodbye()
@@ -224,7 +259,8 @@ func (c *Controller) clear() {
 		c.ctx.SetRGB(0, 0, 0) // Black background
 		c.ctx.Clear()
 	}
-	if c.device != nil {
+	// Drawing would switch a halted panel back on
+	if c.device != nil && !c.blanked {
 		// Create black image
 		img := image.NewGray(image.Rect(0, 0, c.width, c.height))
 		c.device.Draw(c.device.Bounds(), img, image.Point{})
@@ -237,12 +273,20 @@ func (c *Controller) display() error {
 		return fmt.Errorf("display not initialized")
 	}
 
+	// Drawing would switch a halted panel back on
+	if c.blanked {
+		return nil
+	}
+
 	img := c.ctx.Image()
 
 	// Convert to grayscale if needed and apply rotation
 	var finalImg image.Image = img
+	if config.GlobalConfig.OLED.Shift {
+		finalImg = c.shiftImage(finalImg, shiftOffsets[c.shiftIndex%len(shiftOffsets)])
+	}
 	if config.GlobalConfig.OLED.Rotate {
-		finalImg = c.rotateImage180(img)
+		finalImg = c.rotateImage180(finalImg)
 	}
 
 	// Draw to device
@@ -265,6 +309,20 @@ func (c *Controller) rotateImage180(img image.Image) image.Image {
 	return rotated
 }
 
+// shiftImage moves an image by the given offset, clipping pixels pushed off-screen
+func (c *Controller) shiftImage(img image.Image, offset image.Point) image.Image {
+	if offset == (image.Point{}) {
+		return img
+	}
+
+	bounds := img.Bounds()
+	shifted := image.NewRGBA(bounds)
+	draw.Draw(shifted, bounds, image.Black, image.Point{}, draw.Src)
+	draw.Draw(shifted, bounds.Add(offset), img, bounds.Min, draw.Src)
+
+	return shifted
+}
+
 // showWelcome displays the welcome message
 func (c *Controller) showWelcome() {
 	c.clear()
@@ -311,9 +369,27 @@ func (c *Controller) NextSlide() {
 	c.displayCurrentPage()
 }
 
+// GetPage returns the index of the current page
+func (c *Controller) GetPage() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.currentPage
+}
+
+// SetPage switches to the given page
+func (c *Controller) SetPage(page int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if page >= 0 && page < 3 {
+		c.currentPage = page
+	}
+	c.displayCurrentPage()
+}
+
 // displayCurrentPage displays the current page
 func (c *Controller) displayCurrentPage() {
-	if !c.running {
+	if !c.running || c.blanked || c.overlay {
 		return
 	}
 
@@ -344,6 +420,10 @@ func (c *Controller) generatePages() []Page {
 			{X: 0, Y: 32, Text: sysInfo.FormatIPAddress(), Font: 11},
 		},
 	}
+	// A manual fan override replaces the uptime while it's active
+	if status, ok := fan.GetInstance().FormatOverride(); ok {
+		page0.Lines[0].Text = status
+	}
 	pages = append(pages, page0)
 
 	// Page 1: CPU and Memory
@@ -439,6 +519,243 @@ func (c *Controller) autoSliderLoop() {
 	}
 }
 
+// screenSaverLoop shifts the rendered page periodically and blanks the panel
+// when idle or inside the night schedule
+func (c *Controller) screenSaverLoop() {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	lastShift := time.Now()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case now := <-ticker.C:
+			c.mutex.Lock()
+			oledCfg := config.GlobalConfig.OLED
+
+			if c.alert {
+				// Warnings stay visible whatever the schedule says
+			} else if c.shouldBlank(now) {
+				if !c.blanked {
+					c.blank()
+				}
+			} else if c.blanked && !c.forcedBlank && oledCfg.Idle <= 0 {
+				// Night schedule ended and there is no idle timeout to honour
+				c.unblank()
+			}
+
+			shiftInterval := time.Duration(oledCfg.ShiftTime * float64(time.Second))
+			if oledCfg.Shift && shiftInterval > 0 && now.Sub(lastShift) >= shiftInterval {
+				c.shiftIndex = (c.shiftIndex + 1) % len(shiftOffsets)
+				lastShift = now
+				c.displayCurrentPage()
+			}
+			c.mutex.Unlock()
+		}
+	}
+}
+
+// shouldBlank reports whether the panel should be off at the given time
+func (c *Controller) shouldBlank(now time.Time) bool {
+	oledCfg := config.GlobalConfig.OLED
+	idle := time.Duration(oledCfg.Idle * float64(time.Second))
+
+	if oledCfg.InNightSchedule(now) {
+		// A button press wakes the display briefly even at night
+		wakeTime := idle
+		if wakeTime <= 0 {
+			wakeTime = 30 * time.Second
+		}
+		return now.Sub(c.lastActivity) >= wakeTime
+	}
+
+	return idle > 0 && now.Sub(c.lastActivity) >= idle
+}
+
+// blank turns the panel off
+func (c *Controller) blank() {
+	if c.device == nil {
+		return
+	}
+	if err := c.device.Halt(); err != nil {
+		log.Printf("Failed to turn off OLED: %v", err)
+		return
+	}
+	c.blanked = true
+	log.Println("OLED display blanked")
+}
+
+// unblank turns the panel back on and redraws the current page
+func (c *Controller) unblank() {
+	c.blanked = false
+	c.forcedBlank = false
+	if c.overlay {
+		c.display()
+	} else {
+		c.displayCurrentPage()
+	}
+	log.Println("OLED display woken up")
+}
+
+// Wake records user activity and turns the display back on if it was blanked.
+// It returns true when the display was blanked, so the caller can swallow the event.
+func (c *Controller) Wake() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.lastActivity = time.Now()
+	if !c.blanked {
+		return false
+	}
+
+	c.unblank()
+	return true
+}
+
+// Blank turns the display off until the next button event
+func (c *Controller) Blank() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Turn off once the alert is cleared
+	if c.alert {
+		c.alertWasOff = true
+		return
+	}
+
+	c.overlay = false
+	c.blank()
+	if c.blanked {
+		c.forcedBlank = true
+	}
+}
+
+// ShowLines draws up to three lines of text over the information pages.
+// Pages are not redrawn until ClearOverlay is called. An active alert takes
+// precedence and is not replaced.
+func (c *Controller) ShowLines(lines []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.running || c.alert {
+		return
+	}
+	c.drawLines(lines)
+}
+
+// ShowAlert draws a warning like ShowLines, turning the panel on if it was
+// blanked by the screen saver or the user. The warning stays until ClearAlert.
+func (c *Controller) ShowAlert(lines []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.running {
+		return
+	}
+
+	if c.blanked {
+		c.alertWasOff = c.alertWasOff || c.forcedBlank
+		c.blanked = false
+		c.forcedBlank = false
+		log.Println("OLED display woken up for an alert")
+	}
+	c.alert = true
+	c.drawLines(lines)
+}
+
+// ClearAlert removes the warning of ShowAlert and returns to the pages, or
+// turns the panel off again if the user had switched it off
+func (c *Controller) ClearAlert() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.alert {
+		return
+	}
+	c.alert = false
+	c.overlay = false
+	c.lastActivity = time.Now()
+
+	if c.alertWasOff {
+		c.alertWasOff = false
+		c.blank()
+		if c.blanked {
+			c.forcedBlank = true
+		}
+		return
+	}
+	c.displayCurrentPage()
+}
+
+// drawLines renders text lines as an overlay, caller holds the mutex
+func (c *Controller) drawLines(lines []string) {
+	c.overlay = true
+	c.clear()
+	c.ctx.SetRGB(1, 1, 1) // White text
+
+	if font11, exists := c.fonts[11]; exists && font11 != nil {
+		c.ctx.SetFontFace(font11)
+	}
+
+	baselines := []float64{9, 20, 32}
+	for i, text := range lines {
+		if i >= len(baselines) {
+			break
+		}
+		c.ctx.DrawString(text, 0, baselines[i])
+	}
+
+	c.display()
+}
+
+// ClearOverlay removes text shown by ShowLines and redraws the current page
+func (c *Controller) ClearOverlay() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.overlay || c.alert {
+		return
+	}
+	c.overlay = false
+	c.displayCurrentPage()
+}
+
+// IsDisplayOff returns whether the display was turned off by the user
+func (c *Controller) IsDisplayOff() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.forcedBlank
+}
+
+// IsBlanked returns whether the display is currently off
+func (c *Controller) IsBlanked() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.blanked
+}
+
+// SetContrast changes the display brightness (0-255)
+func (c *Controller) SetContrast(level int) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.setContrast(level)
+}
+
+// setContrast sends the contrast level to the device
+func (c *Controller) setContrast(level int) error {
+	if c.device == nil {
+		return fmt.Errorf("display not initialized")
+	}
+	if level < 0 {
+		level = 0
+	} else if level > 255 {
+		level = 255
+	}
+	return c.device.SetContrast(byte(level))
+}
+
 // IsRunning returns whether the OLED controller is running
 func (c *Controller) IsRunning() bool {
 	c.mutex.RLock()
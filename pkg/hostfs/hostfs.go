@@ -0,0 +1,91 @@
+// Package hostfs resolves the absolute paths of procfs, sysfs and /dev
+// against a configurable root, so detection can run against a captured tree
+// of another board.
+package hostfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	root  = "/"
+	mutex sync.RWMutex
+)
+
+// SetRoot makes every path resolve below dir. Set it before detection runs.
+func SetRoot(dir string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if dir == "" {
+		dir = "/"
+	}
+	root = filepath.Clean(dir)
+}
+
+// Root returns the current root
+func Root() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return root
+}
+
+// IsHost reports whether paths resolve on the running system, i.e. devices
+// can be opened and not just listed
+func IsHost() bool {
+	return Root() == "/"
+}
+
+// Path returns where an absolute host path is found below the root
+func Path(path string) string {
+	r := Root()
+	if r == "/" {
+		return path
+	}
+	return filepath.Join(r, path)
+}
+
+// hostPath turns a path below the root back into a host path
+func hostPath(path string) string {
+	r := Root()
+	if r == "/" {
+		return path
+	}
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(path, r), "/")
+}
+
+// ReadFile reads a host file
+func ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(Path(path))
+}
+
+// Open opens a host file for reading
+func Open(path string) (*os.File, error) {
+	return os.Open(Path(path))
+}
+
+// Stat describes a host file
+func Stat(path string) (os.FileInfo, error) {
+	return os.Stat(Path(path))
+}
+
+// ReadDir lists a host directory
+func ReadDir(path string) ([]os.DirEntry, error) {
+	return os.ReadDir(Path(path))
+}
+
+// Readlink returns the target of a host symlink as stored in the link
+func Readlink(path string) (string, error) {
+	return os.Readlink(Path(path))
+}
+
+// Glob matches a host pattern and returns host paths
+func Glob(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(Path(pattern))
+	for i, match := range matches {
+		matches[i] = hostPath(match)
+	}
+	return matches, err
+}
@@ -0,0 +1,172 @@
+package menu
+
+import (
+	"sync"
+)
+
+// visibleLines is how many text lines fit on the 128x32 OLED
+const visibleLines = 3
+
+// Item is a single menu entry
+type Item struct {
+	Label   string
+	Action  string
+	Confirm string // Prompt shown before running the action, empty for none
+}
+
+// Menu tracks the cursor and confirmation state of the on-device menu.
+// Click moves the cursor, double-click selects and long press leaves the menu.
+type Menu struct {
+	items      []Item
+	cursor     int
+	open       bool
+	confirming bool
+	message    []string
+	mutex      sync.RWMutex
+}
+
+// DefaultItems returns the built-in menu entries
+func DefaultItems() []Item {
+	return []Item{
+		{Label: "Fan: auto", Action: "fan-auto"},
+		{Label: "Fan: off", Action: "fan-off"},
+		{Label: "Fan: 100%", Action: "fan-full"},
+		{Label: "Fan: override", Action: "fan-override"},
+		{Label: "Display off", Action: "display-off"},
+		{Label: "Network info", Action: "network"},
+		{Label: "Reboot", Action: "reboot", Confirm: "Reboot?"},
+		{Label: "Power off", Action: "poweroff", Confirm: "Power off?"},
+		{Label: "Exit", Action: "exit"},
+	}
+}
+
+// New creates a menu with the given items
+func New(items []Item) *Menu {
+	return &Menu{
+		items: items,
+	}
+}
+
+// Open shows the menu with the cursor on the first item
+func (m *Menu) Open() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.open = true
+	m.cursor = 0
+	m.confirming = false
+	m.message = nil
+}
+
+// Close leaves the menu
+func (m *Menu) Close() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.open = false
+	m.confirming = false
+	m.message = nil
+}
+
+// IsOpen returns whether the menu is currently shown
+func (m *Menu) IsOpen() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.open
+}
+
+// Next moves the cursor to the next item, wrapping around
+func (m *Menu) Next() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.items) == 0 {
+		return
+	}
+	m.cursor = (m.cursor + 1) % len(m.items)
+}
+
+// Select returns the item under the cursor if it should run now.
+// Items with a confirm prompt return nil the first time and enter the confirm step.
+func (m *Menu) Select() *Item {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.items) == 0 {
+		return nil
+	}
+
+	item := m.items[m.cursor]
+	if item.Confirm != "" && !m.confirming {
+		m.confirming = true
+		return nil
+	}
+
+	m.confirming = false
+	return &item
+}
+
+// Cancel aborts a pending confirm step, returning true if one was pending
+func (m *Menu) Cancel() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.confirming {
+		return false
+	}
+	m.confirming = false
+	return true
+}
+
+// ShowMessage replaces the item list with informational lines until dismissed
+func (m *Menu) ShowMessage(lines []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.message = lines
+}
+
+// DismissMessage clears a shown message, returning true if one was shown
+func (m *Menu) DismissMessage() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.message == nil {
+		return false
+	}
+	m.message = nil
+	return true
+}
+
+// Lines returns the text to render for the current menu state
+func (m *Menu) Lines() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if m.message != nil {
+		return m.message
+	}
+
+	if m.confirming {
+		return []string{
+			m.items[m.cursor].Confirm,
+			"Click: cancel",
+			"Twice: confirm",
+		}
+	}
+
+	// Keep the cursor visible inside a window of visibleLines items
+	start := 0
+	if m.cursor >= visibleLines {
+		start = m.cursor - visibleLines + 1
+	}
+
+	var lines []string
+	for i := start; i < len(m.items) && i < start+visibleLines; i++ {
+		prefix := "  "
+		if i == m.cursor {
+			prefix = "> "
+		}
+		lines = append(lines, prefix+m.items[i].Label)
+	}
+	return lines
+}
@@ -0,0 +1,335 @@
+package power
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Minimal D-Bus client, just enough to call a method on the system bus
+// without pulling in a full D-Bus library.
+
+const (
+	defaultSystemBus = "/var/run/dbus/system_bus_socket"
+	dbusTimeout      = 10 * time.Second
+
+	msgMethodCall   = 1
+	msgMethodReturn = 2
+	msgError        = 3
+
+	fieldPath        = 1
+	fieldInterface   = 2
+	fieldMember      = 3
+	fieldErrorName   = 4
+	fieldReplySerial = 5
+	fieldDestination = 6
+	fieldSignature   = 8
+
+	// Limits on what a peer can make us allocate; the spec allows far more
+	// but the replies we wait for are tiny
+	maxFieldsLen  = 64 << 10
+	maxBodyLen    = 64 << 10
+	maxMessageLen = 128 << 20 // Protocol maximum, larger messages are invalid
+)
+
+// methodCall describes a D-Bus method call with an optional single boolean argument
+type methodCall struct {
+	destination string
+	path        string
+	iface       string
+	member      string
+	args        []bool
+}
+
+// callSystemBus connects to the system bus, authenticates and performs a method call
+func callSystemBus(call methodCall) error {
+	conn, err := net.DialTimeout("unix", systemBusPath(), dbusTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to system bus: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dbusTimeout))
+
+	reader := bufio.NewReader(conn)
+	if err := authenticate(conn, reader); err != nil {
+		return err
+	}
+
+	// Every connection has to say Hello before calling anything else
+	hello := methodCall{
+		destination: "org.freedesktop.DBus",
+		path:        "/org/freedesktop/DBus",
+		iface:       "org.freedesktop.DBus",
+		member:      "Hello",
+	}
+	if err := sendAndWait(conn, reader, hello, 1); err != nil {
+		return fmt.Errorf("D-Bus Hello failed: %v", err)
+	}
+
+	return sendAndWait(conn, reader, call, 2)
+}
+
+// systemBusPath returns the socket of the system bus
+func systemBusPath() string {
+	if addr := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS"); strings.HasPrefix(addr, "unix:path=") {
+		path := strings.TrimPrefix(addr, "unix:path=")
+		if i := strings.Index(path, ","); i >= 0 {
+			path = path[:i]
+		}
+		return path
+	}
+	return defaultSystemBus
+}
+
+// authenticate performs the SASL EXTERNAL handshake with our uid
+func authenticate(conn net.Conn, reader *bufio.Reader) error {
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := conn.Write([]byte("\x00AUTH EXTERNAL " + uid + "\r\n")); err != nil {
+		return fmt.Errorf("D-Bus auth failed: %v", err)
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("D-Bus auth failed: %v", err)
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("D-Bus auth rejected: %s", strings.TrimSpace(line))
+	}
+
+	if _, err := conn.Write([]byte("BEGIN\r\n")); err != nil {
+		return fmt.Errorf("D-Bus auth failed: %v", err)
+	}
+	return nil
+}
+
+// sendAndWait sends a method call and waits for its reply, skipping signals
+func sendAndWait(conn net.Conn, reader *bufio.Reader, call methodCall, serial uint32) error {
+	if _, err := conn.Write(encodeMethodCall(call, serial)); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := readMessage(reader)
+		if err != nil {
+			return err
+		}
+		if msg.replySerial != serial {
+			continue
+		}
+
+		switch msg.msgType {
+		case msgMethodReturn:
+			return nil
+		case msgError:
+			if message := decodeFirstString(msg.body, msg.order); message != "" {
+				return fmt.Errorf("%s: %s", msg.errorName, message)
+			}
+			return fmt.Errorf("%s", msg.errorName)
+		}
+	}
+}
+
+// encodeMethodCall marshals a little-endian METHOD_CALL message
+func encodeMethodCall(call methodCall, serial uint32) []byte {
+	var body bytes.Buffer
+	for _, arg := range call.args {
+		value := uint32(0)
+		if arg {
+			value = 1
+		}
+		binary.Write(&body, binary.LittleEndian, value)
+	}
+
+	var fields bytes.Buffer
+	writeField(&fields, fieldPath, "o", call.path)
+	writeField(&fields, fieldInterface, "s", call.iface)
+	writeField(&fields, fieldMember, "s", call.member)
+	writeField(&fields, fieldDestination, "s", call.destination)
+	if len(call.args) > 0 {
+		writeField(&fields, fieldSignature, "g", strings.Repeat("b", len(call.args)))
+	}
+
+	var msg bytes.Buffer
+	msg.Write([]byte{'l', msgMethodCall, 0, 1})
+	binary.Write(&msg, binary.LittleEndian, uint32(body.Len()))
+	binary.Write(&msg, binary.LittleEndian, serial)
+	binary.Write(&msg, binary.LittleEndian, uint32(fields.Len()))
+	msg.Write(fields.Bytes())
+	pad(&msg, 8)
+	msg.Write(body.Bytes())
+
+	return msg.Bytes()
+}
+
+// writeField appends a header field struct (yv); offsets are relative to the
+// start of the array, which itself starts 8-aligned in the message
+func writeField(buf *bytes.Buffer, code byte, signature, value string) {
+	pad(buf, 8)
+	buf.Write([]byte{code, 1, signature[0], 0})
+	if signature == "g" {
+		buf.WriteByte(byte(len(value)))
+	} else {
+		pad(buf, 4)
+		binary.Write(buf, binary.LittleEndian, uint32(len(value)))
+	}
+	buf.WriteString(value)
+	buf.WriteByte(0)
+}
+
+// pad aligns the buffer length to the given boundary
+func pad(buf *bytes.Buffer, align int) {
+	for buf.Len()%align != 0 {
+		buf.WriteByte(0)
+	}
+}
+
+// message is the part of a received message we care about
+type message struct {
+	msgType     byte
+	replySerial uint32
+	errorName   string
+	body        []byte // Empty when larger than maxBodyLen
+	order       binary.ByteOrder
+}
+
+// readMessage reads one message. Bodies larger than maxBodyLen are skipped.
+func readMessage(reader *bufio.Reader) (message, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return message{}, err
+	}
+
+	var order binary.ByteOrder
+	switch header[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return message{}, fmt.Errorf("invalid D-Bus byte order %q", header[0])
+	}
+	msgType := header[1]
+	bodyLen := order.Uint32(header[4:8])
+	fieldsLen := order.Uint32(header[12:16])
+	if fieldsLen > maxFieldsLen {
+		return message{}, fmt.Errorf("D-Bus header fields too long (%d bytes)", fieldsLen)
+	}
+	if bodyLen > maxMessageLen {
+		return message{}, fmt.Errorf("D-Bus message too long (%d bytes)", bodyLen)
+	}
+
+	// Fields array, then padding to 8 bytes, then the body
+	fields := make([]byte, (16+int(fieldsLen)+7)&^7-16)
+	if _, err := io.ReadFull(reader, fields); err != nil {
+		return message{}, err
+	}
+	fields = fields[:fieldsLen]
+
+	var body []byte
+	if bodyLen <= maxBodyLen {
+		body = make([]byte, bodyLen)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return message{}, err
+		}
+	} else if _, err := io.CopyN(io.Discard, reader, int64(bodyLen)); err != nil {
+		return message{}, err
+	}
+
+	replySerial, errorName, err := parseHeaderFields(fields, order)
+	if err != nil {
+		return message{}, err
+	}
+	return message{
+		msgType:     msgType,
+		replySerial: replySerial,
+		errorName:   errorName,
+		body:        body,
+		order:       order,
+	}, nil
+}
+
+// parseHeaderFields returns the reply serial and error name from a header
+// field array, checking every offset against its length
+func parseHeaderFields(fields []byte, order binary.ByteOrder) (uint32, string, error) {
+	var replySerial uint32
+	var errorName string
+	for pos := 0; ; {
+		pos = (pos + 7) &^ 7
+		if pos >= len(fields) {
+			break
+		}
+		if pos+2 > len(fields) {
+			return 0, "", fmt.Errorf("truncated D-Bus header field at offset %d", pos)
+		}
+		code := fields[pos]
+		sigLen := int(fields[pos+1])
+		if pos+2+sigLen+1 > len(fields) {
+			return 0, "", fmt.Errorf("truncated D-Bus header field at offset %d", pos)
+		}
+		sig := string(fields[pos+2 : pos+2+sigLen])
+		pos += 2 + sigLen + 1
+
+		switch sig {
+		case "u":
+			pos = (pos + 3) &^ 3
+			if pos+4 > len(fields) {
+				return 0, "", fmt.Errorf("truncated D-Bus header field at offset %d", pos)
+			}
+			value := order.Uint32(fields[pos : pos+4])
+			pos += 4
+			if code == fieldReplySerial {
+				replySerial = value
+			}
+		case "s", "o":
+			pos = (pos + 3) &^ 3
+			value, next, ok := readString(fields, pos, order)
+			if !ok {
+				return 0, "", fmt.Errorf("truncated D-Bus header field at offset %d", pos)
+			}
+			pos = next
+			if code == fieldErrorName {
+				errorName = value
+			}
+		case "g":
+			if pos >= len(fields) || pos+int(fields[pos])+2 > len(fields) {
+				return 0, "", fmt.Errorf("truncated D-Bus header field at offset %d", pos)
+			}
+			pos += int(fields[pos]) + 2
+		default:
+			// Unknown field type, nothing we need follows reliably
+			return replySerial, errorName, nil
+		}
+	}
+	return replySerial, errorName, nil
+}
+
+// readString decodes a length-prefixed, NUL-terminated string at pos and
+// returns it with the offset that follows it
+func readString(data []byte, pos int, order binary.ByteOrder) (string, int, bool) {
+	if pos+4 > len(data) {
+		return "", 0, false
+	}
+	strLen := uint64(order.Uint32(data[pos : pos+4]))
+	if strLen+1 > uint64(len(data)-pos-4) {
+		return "", 0, false
+	}
+	end := pos + 4 + int(strLen)
+	return string(data[pos+4 : end]), end + 1, true
+}
+
+// decodeFirstString returns the leading string argument of a body
+func decodeFirstString(body []byte, order binary.ByteOrder) string {
+	value, _, ok := readString(body, 0, order)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,151 @@
+package power
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// bigEndianError builds a big-endian ERROR reply to serial 2
+func bigEndianError(name, text string) []byte {
+	var fields bytes.Buffer
+	fields.Write([]byte{fieldErrorName, 1, 's', 0})
+	binary.Write(&fields, binary.BigEndian, uint32(len(name)))
+	fields.WriteString(name)
+	fields.WriteByte(0)
+	pad(&fields, 8)
+	fields.Write([]byte{fieldReplySerial, 1, 'u', 0})
+	binary.Write(&fields, binary.BigEndian, uint32(2))
+
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, uint32(len(text)))
+	body.WriteString(text)
+	body.WriteByte(0)
+
+	var msg bytes.Buffer
+	msg.Write([]byte{'B', msgError, 0, 1})
+	binary.Write(&msg, binary.BigEndian, uint32(body.Len()))
+	binary.Write(&msg, binary.BigEndian, uint32(7))
+	binary.Write(&msg, binary.BigEndian, uint32(fields.Len()))
+	msg.Write(fields.Bytes())
+	pad(&msg, 8)
+	msg.Write(body.Bytes())
+	return msg.Bytes()
+}
+
+func TestReadMessageBigEndian(t *testing.T) {
+	data := bigEndianError("org.freedesktop.DBus.Error.AccessDenied", "denied")
+	msg, err := readMessage(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.msgType != msgError || msg.replySerial != 2 || msg.errorName != "org.freedesktop.DBus.Error.AccessDenied" {
+		t.Errorf("got %+v", msg)
+	}
+	if text := decodeFirstString(msg.body, msg.order); text != "denied" {
+		t.Errorf("body = %q, want %q", text, "denied")
+	}
+}
+
+func TestReadMessageMalformed(t *testing.T) {
+	valid := bigEndianError("org.example.Error", "denied")
+
+	tests := []struct {
+		name   string
+		data   func() []byte
+		errSub string
+	}{
+		{
+			name:   "unknown byte order",
+			data:   func() []byte { d := bytes.Clone(valid); d[0] = 'x'; return d },
+			errSub: "byte order",
+		},
+		{
+			name: "huge fields length",
+			data: func() []byte {
+				d := bytes.Clone(valid)
+				binary.BigEndian.PutUint32(d[12:16], 0xffffffff)
+				return d
+			},
+			errSub: "too long",
+		},
+		{
+			name: "huge body length",
+			data: func() []byte {
+				d := bytes.Clone(valid)
+				binary.BigEndian.PutUint32(d[4:8], 0xffffffff)
+				return d
+			},
+			errSub: "too long",
+		},
+		{
+			name: "string past the fields",
+			data: func() []byte {
+				d := bytes.Clone(valid)
+				binary.BigEndian.PutUint32(d[20:24], 0x7fffffff)
+				return d
+			},
+			errSub: "truncated",
+		},
+		{
+			name: "signature past the fields",
+			data: func() []byte {
+				d := bytes.Clone(valid)
+				d[17] = 0xff
+				return d
+			},
+			errSub: "truncated",
+		},
+		{
+			name:   "short read",
+			data:   func() []byte { return valid[:len(valid)-3] },
+			errSub: "EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readMessage(bufio.NewReader(bytes.NewReader(tt.data())))
+			if err == nil || !strings.Contains(err.Error(), tt.errSub) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.errSub)
+			}
+		})
+	}
+}
+
+func TestReadMessageRoundTrip(t *testing.T) {
+	call := methodCall{
+		destination: "org.freedesktop.login1",
+		path:        "/org/freedesktop/login1",
+		iface:       "org.freedesktop.login1.Manager",
+		member:      "PowerOff",
+		args:        []bool{false},
+	}
+	msg, err := readMessage(bufio.NewReader(bytes.NewReader(encodeMethodCall(call, 3))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.msgType != msgMethodCall || len(msg.body) != 4 {
+		t.Errorf("got %+v", msg)
+	}
+}
+
+func TestDecodeFirstString(t *testing.T) {
+	tests := []struct {
+		body []byte
+		want string
+	}{
+		{nil, ""},
+		{[]byte{5, 0, 0}, ""},
+		{[]byte{5, 0, 0, 0, 'a', 'b'}, ""},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 'a'}, ""},
+		{[]byte{2, 0, 0, 0, 'o', 'k', 0}, "ok"},
+	}
+	for _, tt := range tests {
+		if got := decodeFirstString(tt.body, binary.LittleEndian); got != tt.want {
+			t.Errorf("decodeFirstString(%v) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
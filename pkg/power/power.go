@@ -0,0 +1,55 @@
+package power
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// Action is a system power state change
+type Action string
+
+const (
+	Reboot   Action = "reboot"
+	PowerOff Action = "poweroff"
+)
+
+// logindMethods maps actions to org.freedesktop.login1.Manager methods
+var logindMethods = map[Action]string{
+	Reboot:   "Reboot",
+	PowerOff: "PowerOff",
+}
+
+// Execute asks systemd-logind over D-Bus to reboot or power off the system,
+// falling back to systemctl when logind can't be reached
+func Execute(action Action) error {
+	method, exists := logindMethods[action]
+	if !exists {
+		return fmt.Errorf("unknown power action %q", action)
+	}
+
+	err := callSystemBus(methodCall{
+		destination: "org.freedesktop.login1",
+		path:        "/org/freedesktop/login1",
+		iface:       "org.freedesktop.login1.Manager",
+		member:      method,
+		args:        []bool{false}, // Not interactive, we run as root
+	})
+	if err == nil {
+		log.Printf("%s scheduled via logind", action)
+		return nil
+	}
+	log.Printf("logind %s failed, falling back to systemctl: %v", method, err)
+
+	output, cmdErr := exec.Command("systemctl", string(action)).CombinedOutput()
+	if cmdErr != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("systemctl %s failed: %v: %s", action, cmdErr, msg)
+		}
+		return fmt.Errorf("systemctl %s failed: %v", action, cmdErr)
+	}
+
+	log.Printf("%s scheduled via systemctl", action)
+	return nil
+}
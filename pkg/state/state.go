@@ -0,0 +1,97 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultPath is where the runtime state is kept between restarts
+const DefaultPath = "/var/lib/rockpi-penta/state.json"
+
+// State is the runtime state restored at startup
+type State struct {
+	FanEnabled bool      `json:"fan_enabled"`
+	FanFull    bool      `json:"fan_full,omitempty"` // Fan mode 100% chosen from the menu
+	Override   *Override `json:"override,omitempty"`
+	Page       int       `json:"page"`
+	DisplayOff bool      `json:"display_off"`
+}
+
+// Override is an active manual fan override
+type Override struct {
+	Duty  float64   `json:"duty"`  // Fan power in percent
+	Until time.Time `json:"until"` // Automatic control resumes after this
+}
+
+// Equal reports whether two states would be written identically
+func (s State) Equal(other State) bool {
+	if (s.Override == nil) != (other.Override == nil) {
+		return false
+	}
+	if s.Override != nil && (s.Override.Duty != other.Override.Duty || !s.Override.Until.Equal(other.Override.Until)) {
+		return false
+	}
+	return s.FanEnabled == other.FanEnabled && s.FanFull == other.FanFull && s.Page == other.Page && s.DisplayOff == other.DisplayOff
+}
+
+// Load reads the state file, a missing file returns os.ErrNotExist
+func Load(path string) (State, error) {
+	var s State
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("invalid state file %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the state file atomically: the data goes to a temporary file
+// in the same directory, which is synced and renamed over the old file
+func Save(path string, s State) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".state-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to set state file mode: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace state file: %v", err)
+	}
+
+	// Make the rename itself durable
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
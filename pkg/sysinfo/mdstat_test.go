@@ -0,0 +1,107 @@
+package sysinfo
+
+import "testing"
+
+func TestParseMDStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		syncing bool
+	}{
+		{
+			name:    "no arrays",
+			content: "Personalities : \nunused devices: <none>\n",
+		},
+		{
+			name: "clean array",
+			content: `Personalities : [raid1]
+md0 : active raid1 sdb1[1] sda1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+      bitmap: 0/8 pages [0KB], 65536KB chunk
+
+unused devices: <none>
+`,
+		},
+		{
+			name: "resync",
+			content: `Personalities : [raid1]
+md0 : active raid1 sdb1[1] sda1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+      [=>...................]  resync =  7.5% (73296768/976630464) finish=92.1min speed=163404K/sec
+      bitmap: 8/8 pages [32KB], 65536KB chunk
+
+unused devices: <none>
+`,
+			want:    "md0 resync 7.5%",
+			syncing: true,
+		},
+		{
+			name: "recovery on the second array",
+			content: `Personalities : [raid1] [raid5]
+md0 : active raid1 sdb1[1] sda1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+
+md1 : active raid5 sde1[4] sdd1[2] sdc1[1]
+      1953260544 blocks super 1.2 level 5, 512k chunk, algorithm 2 [3/2] [UU_]
+      [==========>..........]  recovery = 52.3% (510907392/976630272) finish=41.2min speed=188305K/sec
+
+unused devices: <none>
+`,
+			want:    "md1 recovery 52.3%",
+			syncing: true,
+		},
+		{
+			name: "check",
+			content: `Personalities : [raid1]
+md127 : active raid1 sdb[1] sda[0]
+      3906887488 blocks super 1.2 [2/2] [UU]
+      [>....................]  check =  0.1% (4523008/3906887488) finish=359.1min speed=181120K/sec
+`,
+			want:    "md127 check 0.1%",
+			syncing: true,
+		},
+		{
+			name: "delayed resync",
+			content: `Personalities : [raid1]
+md0 : active raid1 sdb1[1] sda1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+      [=>...................]  resync =  9.0% (87898112/976630464) finish=80.0min speed=185000K/sec
+
+md1 : active raid1 sdd1[1] sdc1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+        resync=DELAYED
+`,
+			want:    "md0 resync 9.0%",
+			syncing: true,
+		},
+		{
+			name: "only delayed resync",
+			content: `Personalities : [raid1]
+md1 : active raid1 sdd1[1] sdc1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+        resync=DELAYED
+`,
+			want:    "md1 resync pending",
+			syncing: true,
+		},
+		{
+			name: "pending resync",
+			content: `md2 : active (auto-read-only) raid1 sdf1[1] sde1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+        resync=PENDING
+`,
+			want:    "md2 resync pending",
+			syncing: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, syncing := parseMDStat(tt.content)
+			if got != tt.want || syncing != tt.syncing {
+				t.Errorf("parseMDStat() = %q, %t, want %q, %t", got, syncing, tt.want, tt.syncing)
+			}
+		})
+	}
+}
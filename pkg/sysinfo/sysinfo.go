@@ -3,16 +3,19 @@ package sysinfo
 import (
 	"bufio"
 	"fmt"
+	"math"
 	"net"
 	"os"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/config"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hardware/disk"
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hostfs"
 )
 
 type SystemInfo struct {
@@ -56,10 +59,9 @@ func (s *SystemInfo) Update() error {
 
 	now := time.Now()
 	
-	// Update basic info every time
-	if err := s.updateBasicInfo(); err != nil {
-		return err
-	}
+	// Update basic info every time. A failed temperature read is reported
+	// after the disks are refreshed so the disk page doesn't go stale.
+	err := s.updateBasicInfo()
 
 	// Update disk info every 30 seconds
 	if now.Sub(s.cacheDisk) > 30*time.Second {
@@ -68,7 +70,7 @@ func (s *SystemInfo) Update() error {
 	}
 
 	s.cacheTime = now
-	return nil
+	return err
 }
 
 func (s *SystemInfo) updateBasicInfo() error {
@@ -78,8 +80,11 @@ func (s *SystemInfo) updateBasicInfo() error {
 	}
 
 	// Get CPU temperature
-	if temp, err := s.getCPUTemp(); err == nil {
+	var tempErr error
+	if temp, err := ReadCPUTemperature(); err == nil {
 		s.CPUTemp = temp
+	} else {
+		tempErr = fmt.Errorf("failed to read CPU temperature: %v", err)
 	}
 
 	// Get IP address
@@ -98,10 +103,11 @@ func (s *SystemInfo) updateBasicInfo() error {
 		s.MemoryTotal = total
 	}
 
-	return nil
+	return tempErr
 }
 
 func (s *SystemInfo) updateDiskInfo() {
+	previous := s.DiskUsage
 	s.DiskUsage = make(map[string]DiskInfo)
 	
 	// Get root disk usage
@@ -110,9 +116,21 @@ func (s *SystemInfo) updateDiskInfo() {
 	}
 
 	// Get SATA disk usage
+	diskPower := disk.GetInstance()
 	devices := config.GlobalConfig.GetDiskDevices()
 	for _, device := range devices {
-		mountPoint := fmt.Sprintf("/dev/%s", device)
+		// Don't wake a sleeping disk just to refresh its usage
+		if diskPower.IsSleeping(device) {
+			if info, exists := previous[device]; exists {
+				s.DiskUsage[device] = info
+			}
+			continue
+		}
+
+		mountPoint, err := diskMountPoint(device)
+		if err != nil {
+			continue
+		}
 		if info, err := s.getDiskInfo(mountPoint); err == nil {
 			s.DiskUsage[device] = info
 		}
@@ -120,17 +138,42 @@ func (s *SystemInfo) updateDiskInfo() {
 }
 
 func (s *SystemInfo) getUptime() (string, error) {
-	cmd := exec.Command("sh", "-c", "uptime | sed 's/.*up \\([^,]*\\), .*/\\1/'")
-	output, err := cmd.Output()
+	data, err := hostfs.ReadFile("/proc/uptime")
 	if err != nil {
 		return "", err
 	}
-	uptime := strings.TrimSpace(string(output))
-	return fmt.Sprintf("Uptime: %s", uptime), nil
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unexpected /proc/uptime format")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse uptime: %v", err)
+	}
+	return fmt.Sprintf("Uptime: %s", formatUptime(time.Duration(seconds*float64(time.Second)))), nil
+}
+
+// formatUptime shortens an uptime like uptime(1) does: days, then hours and
+// minutes, then minutes
+func formatUptime(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	switch {
+	case days == 1:
+		return "1 day"
+	case days > 1:
+		return fmt.Sprintf("%d days", days)
+	case hours > 0:
+		return fmt.Sprintf("%d:%02d", hours, minutes)
+	}
+	return fmt.Sprintf("%d min", minutes)
 }
 
-func (s *SystemInfo) getCPUTemp() (float64, error) {
-	data, err := os.ReadFile("/sys/class/thermal/thermal_zone0/temp")
+// ReadCPUTemperature reads the CPU temperature directly from sysfs
+func ReadCPUTemperature() (float64, error) {
+	data, err := hostfs.ReadFile("/sys/class/thermal/thermal_zone0/temp")
 	if err != nil {
 		return 0, err
 	}
@@ -145,118 +188,254 @@ func (s *SystemInfo) getCPUTemp() (float64, error) {
 }
 
 func (s *SystemInfo) getIPAddress() (string, error) {
-	cmd := exec.Command("hostname", "-I")
-	output, err := cmd.Output()
+	ifaces, err := net.Interfaces()
 	if err != nil {
 		return "", err
 	}
-	
-	ips := strings.Fields(string(output))
-	if len(ips) > 0 {
-		return fmt.Sprintf("IP %s", ips[0]), nil
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		if ip := firstIPv4(addrs); ip != "" {
+			return fmt.Sprintf("IP %s", ip), nil
+		}
 	}
-	
-	// Fallback to network interface detection
-	addrs, err := net.InterfaceAddrs()
+
+	return "IP N/A", nil
+}
+
+// firstIPv4 returns the first global IPv4 address, like hostname -I lists them
+func firstIPv4(addrs []net.Addr) string {
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil && ipnet.IP.IsGlobalUnicast() {
+			return ipnet.IP.String()
+		}
+	}
+	return ""
+}
+
+// GetNetworkDetails returns the hostname followed by one line per IPv4 interface
+func (s *SystemInfo) GetNetworkDetails() []string {
+	var details []string
+
+	if hostname, err := os.Hostname(); err == nil {
+		details = append(details, hostname)
+	}
+
+	ifaces, err := net.Interfaces()
 	if err != nil {
-		return "", err
+		return details
 	}
-	
-	for _, addr := range addrs {
-		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				return fmt.Sprintf("IP %s", ipnet.IP.String()), nil
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				details = append(details, fmt.Sprintf("%s %s", iface.Name, ipnet.IP.String()))
 			}
 		}
 	}
-	
-	return "IP N/A", nil
+
+	return details
 }
 
 func (s *SystemInfo) getCPULoad() (float64, error) {
-	cmd := exec.Command("sh", "-c", "uptime | awk '{printf \"%.2f\", $(NF-2)}'")
-	output, err := cmd.Output()
+	data, err := hostfs.ReadFile("/proc/loadavg")
 	if err != nil {
 		return 0, err
 	}
-	
-	loadStr := strings.TrimSpace(string(output))
-	return strconv.ParseFloat(loadStr, 64)
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected /proc/loadavg format")
+	}
+	// The one minute average
+	return strconv.ParseFloat(fields[0], 64)
 }
 
+// getMemoryInfo returns the used and total memory in MB. Used memory is
+// everything that isn't available, like free(1) reports it.
 func (s *SystemInfo) getMemoryInfo() (int, int, error) {
-	cmd := exec.Command("sh", "-c", "free -m | awk 'NR==2{printf \"%s %s\", $3,$2}'")
-	output, err := cmd.Output()
+	file, err := hostfs.Open("/proc/meminfo")
 	if err != nil {
 		return 0, 0, err
 	}
-	
-	parts := strings.Fields(string(output))
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("unexpected memory info format")
+	defer file.Close()
+
+	values := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// MemTotal:        3880428 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if kb, err := strconv.Atoi(fields[1]); err == nil {
+			values[strings.TrimSuffix(fields[0], ":")] = kb
+		}
 	}
-	
-	used, err1 := strconv.Atoi(parts[0])
-	total, err2 := strconv.Atoi(parts[1])
-	if err1 != nil || err2 != nil {
-		return 0, 0, fmt.Errorf("failed to parse memory values")
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
 	}
-	
-	return used, total, nil
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return 0, 0, fmt.Errorf("MemTotal missing from /proc/meminfo")
+	}
+	available, ok := values["MemAvailable"]
+	if !ok {
+		// Kernels before 3.14 don't report MemAvailable
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+
+	return (total - available) / 1024, total / 1024, nil
 }
 
+// getDiskInfo reports the usage of the filesystem mounted at mountPoint
 func (s *SystemInfo) getDiskInfo(mountPoint string) (DiskInfo, error) {
-	var info DiskInfo
-	
-	cmd := exec.Command("df", "-h", mountPoint)
-	output, err := cmd.Output()
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(hostfs.Path(mountPoint), &stat); err != nil {
+		return DiskInfo{}, err
+	}
+	size := uint64(stat.Bsize)
+	return newDiskInfo(stat.Blocks*size, stat.Bfree*size, stat.Bavail*size), nil
+}
+
+// newDiskInfo formats filesystem sizes in bytes like df -h does. The
+// percentage excludes the blocks reserved for root.
+func newDiskInfo(total, free, avail uint64) DiskInfo {
+	used := total - free
+	percentage := 0
+	if used+avail > 0 {
+		percentage = int((used*100 + used + avail - 1) / (used + avail))
+	}
+	return DiskInfo{
+		Used:       humanSize(used),
+		Total:      humanSize(total),
+		Percentage: fmt.Sprintf("%d%%", percentage),
+	}
+}
+
+// humanSize rounds a size up to one decimal below 10 and to a whole number
+// above, with a 1024-based unit suffix
+func humanSize(bytes uint64) string {
+	if bytes < 1024 {
+		return strconv.FormatUint(bytes, 10)
+	}
+	value := float64(bytes)
+	unit := 0
+	for value >= 1024 && unit < len(sizeUnits)-1 {
+		value /= 1024
+		unit++
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%s", math.Ceil(value*10)/10, sizeUnits[unit])
+	}
+	return fmt.Sprintf("%.0f%s", math.Ceil(value), sizeUnits[unit])
+}
+
+var sizeUnits = []string{"", "K", "M", "G", "T", "P"}
+
+// diskMountPoint returns where a SATA disk, or the first of its partitions,
+// is mounted according to /proc/mounts
+func diskMountPoint(device string) (string, error) {
+	data, err := hostfs.ReadFile("/proc/mounts")
 	if err != nil {
-		return info, err
+		return "", err
 	}
-	
-	lines := strings.Split(string(output), "\n")
-	if len(lines) < 2 {
-		return info, fmt.Errorf("unexpected df output")
+
+	disk := "/dev/" + device
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		source := fields[0]
+		if source == disk || (strings.HasPrefix(source, disk) && isDigits(source[len(disk):])) {
+			return mountEscapes.Replace(fields[1]), nil
+		}
 	}
-	
-	// Parse the second line (actual data)
-	fields := strings.Fields(lines[1])
-	if len(fields) < 5 {
-		return info, fmt.Errorf("unexpected df output format")
+	return "", fmt.Errorf("%s is not mounted", device)
+}
+
+// mountEscapes decodes the octal escapes of /proc/mounts
+var mountEscapes = strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
 	}
-	
-	info.Total = fields[1]
-	info.Used = fields[2]
-	info.Percentage = fields[4]
-	
-	return info, nil
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // GetBlockDevices updates the list of SATA block devices
 func (s *SystemInfo) GetBlockDevices() []string {
-	cmd := exec.Command("lsblk", "-no", "NAME")
-	output, err := cmd.Output()
+	entries, err := hostfs.ReadDir("/sys/block")
 	if err != nil {
 		return []string{}
 	}
-	
+
 	var devices []string
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	for scanner.Scan() {
-		device := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(device, "sd") {
-			devices = append(devices, device)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "sd") {
+			devices = append(devices, entry.Name())
 		}
 	}
-	
+
 	// Update global config
 	if config.GlobalConfig != nil {
 		config.GlobalConfig.SetDiskDevices(devices)
 	}
-	
+
 	return devices
 }
 
+// GetRAIDSyncStatus reports whether any md array is resyncing, recovering,
+// reshaping or checking, along with a short description like "md0 resync 12.6%"
+func (s *SystemInfo) GetRAIDSyncStatus() (string, bool) {
+	data, err := hostfs.ReadFile("/proc/mdstat")
+	if err != nil {
+		return "", false
+	}
+	return parseMDStat(string(data))
+}
+
+// mdSyncRegex matches the progress of a running md sync operation
+var mdSyncRegex = regexp.MustCompile(`(resync|recovery|reshape|check)\s*=\s*([0-9.]+%)`)
+
+// parseMDStat extracts the first running sync operation from /proc/mdstat content
+func parseMDStat(content string) (string, bool) {
+	array := ""
+	for _, line := range strings.Split(content, "\n") {
+		if fields := strings.Fields(line); len(fields) > 2 && fields[1] == ":" && strings.HasPrefix(fields[0], "md") {
+			array = fields[0]
+			continue
+		}
+		if matches := mdSyncRegex.FindStringSubmatch(line); len(matches) > 2 {
+			return fmt.Sprintf("%s %s %s", array, matches[1], matches[2]), true
+		}
+		if strings.Contains(line, "resync=DELAYED") || strings.Contains(line, "resync=PENDING") {
+			return fmt.Sprintf("%s resync pending", array), true
+		}
+	}
+	return "", false
+}
+
 // FormatTemperature formats temperature based on configuration
 func (s *SystemInfo) FormatTemperature() string {
 	s.cacheMutex.RLock()
@@ -311,12 +490,17 @@ func (s *SystemInfo) FormatDiskUsage() ([]string, []string) {
 		values = append(values, rootInfo.Percentage)
 	}
 	
-	// Add SATA disks
+	// Add SATA disks, marking sleeping ones with a trailing "z"
+	diskPower := disk.GetInstance()
 	devices := config.GlobalConfig.GetDiskDevices()
 	for _, device := range devices {
 		if info, exists := s.DiskUsage[device]; exists {
+			value := info.Percentage
+			if diskPower.IsSleeping(device) {
+				value += "z"
+			}
 			keys = append(keys, device+":")
-			values = append(values, info.Percentage)
+			values = append(values, value)
 		}
 	}
 	
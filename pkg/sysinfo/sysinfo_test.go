@@ -0,0 +1,134 @@
+package sysinfo
+
+import (
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GuilhermeVozniak/rockpi-penta-golang/pkg/hostfs"
+)
+
+// useFixture resolves procfs and sysfs below testdata
+func useFixture(t *testing.T) {
+	t.Helper()
+	root, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostfs.SetRoot(root)
+	t.Cleanup(func() { hostfs.SetRoot("/") })
+}
+
+func TestProcFixtures(t *testing.T) {
+	useFixture(t)
+	s := &SystemInfo{}
+
+	uptime, err := s.getUptime()
+	if err != nil || uptime != "Uptime: 3 days" {
+		t.Errorf("getUptime() = %q, %v, want \"Uptime: 3 days\"", uptime, err)
+	}
+
+	load, err := s.getCPULoad()
+	if err != nil || load != 0.42 {
+		t.Errorf("getCPULoad() = %v, %v, want 0.42", load, err)
+	}
+
+	used, total, err := s.getMemoryInfo()
+	if err != nil || used != 1137 || total != 3789 {
+		t.Errorf("getMemoryInfo() = %d, %d, %v, want 1137, 3789", used, total, err)
+	}
+
+	devices := s.GetBlockDevices()
+	if want := []string{"sda", "sdb", "sdc", "sdd"}; !reflect.DeepEqual(devices, want) {
+		t.Errorf("GetBlockDevices() = %v, want %v", devices, want)
+	}
+}
+
+func TestDiskMountPoint(t *testing.T) {
+	useFixture(t)
+
+	tests := []struct {
+		device string
+		want   string
+		ok     bool
+	}{
+		{"sda", "/srv/dev-disk-by-uuid-1f3c", true},
+		{"sdb", "/mnt/backup disk", true},
+		{"sdc", "/mnt/sdc1", true},
+		{"sdd", "", false},
+		{"sd", "", false},
+	}
+	for _, tt := range tests {
+		got, err := diskMountPoint(tt.device)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("diskMountPoint(%q) = %q, %v, want %q", tt.device, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		uptime time.Duration
+		want   string
+	}{
+		{42 * time.Second, "0 min"},
+		{17 * time.Minute, "17 min"},
+		{4*time.Hour + 5*time.Minute, "4:05"},
+		{25 * time.Hour, "1 day"},
+		{80 * time.Hour, "3 days"},
+	}
+	for _, tt := range tests {
+		if got := formatUptime(tt.uptime); got != tt.want {
+			t.Errorf("formatUptime(%s) = %q, want %q", tt.uptime, got, tt.want)
+		}
+	}
+}
+
+func TestNewDiskInfo(t *testing.T) {
+	const gib = 1 << 30
+
+	tests := []struct {
+		name               string
+		total, free, avail uint64
+		want               DiskInfo
+	}{
+		{"empty", 0, 0, 0, DiskInfo{Used: "0", Total: "0", Percentage: "0%"}},
+		{"reserved blocks", 100 * gib, 60 * gib, 55 * gib, DiskInfo{Used: "40G", Total: "100G", Percentage: "43%"}},
+		{"rounds up", 3*gib + 1, 2 * gib, 2 * gib, DiskInfo{Used: "1.1G", Total: "3.1G", Percentage: "34%"}},
+		{"terabytes", 1800 * gib, 900 * gib, 900 * gib, DiskInfo{Used: "900G", Total: "1.8T", Percentage: "50%"}},
+		{"full", 10 * gib, 0, 0, DiskInfo{Used: "10G", Total: "10G", Percentage: "100%"}},
+	}
+	for _, tt := range tests {
+		if got := newDiskInfo(tt.total, tt.free, tt.avail); got != tt.want {
+			t.Errorf("%s: newDiskInfo() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFirstIPv4(t *testing.T) {
+	addr := func(cidr string) net.Addr {
+		ip, ipnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ipnet.IP = ip
+		return ipnet
+	}
+
+	tests := []struct {
+		addrs []net.Addr
+		want  string
+	}{
+		{nil, ""},
+		{[]net.Addr{addr("fe80::1/64"), addr("192.168.1.20/24")}, "192.168.1.20"},
+		{[]net.Addr{addr("169.254.3.4/16"), addr("10.0.0.5/8")}, "10.0.0.5"},
+		{[]net.Addr{addr("2001:db8::5/64")}, ""},
+	}
+	for _, tt := range tests {
+		if got := firstIPv4(tt.addrs); got != tt.want {
+			t.Errorf("firstIPv4(%v) = %q, want %q", tt.addrs, got, tt.want)
+		}
+	}
+}
@@ -18,6 +18,7 @@ BINARY_PATH="/usr/local/bin/rockpi-penta"
 SERVICE_FILE="/etc/systemd/system/rockpi-penta.service"
 CONFIG_FILE="/etc/rockpi-penta.conf"
 ENV_FILE="/etc/rockpi-penta.env"
+STATE_DIR="/var/lib/rockpi-penta"
 
 print_info() {
     echo -e "${BLUE}[INFO]${NC} $1"
@@ -132,6 +133,13 @@ remove_config_files() {
     else
         print_info "Environment file not found: $ENV_FILE"
     fi
+
+    # Remove saved runtime state
+    if [[ -d "$STATE_DIR" ]]; then
+        print_info "Removing state directory: $STATE_DIR"
+        rm -rf "$STATE_DIR"
+        print_success "State directory removed"
+    fi
 }
 
 reload_systemd() {